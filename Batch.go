@@ -0,0 +1,150 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+// Sets all the given entries under a single lock acquisition.
+// Panics if any key cannot be used as a map key, before any entry is set unless interceptors are installed, see WithInterceptors.
+// Panics with ErrFrozen if the map is frozen.
+func (this *ConcurrentMap) SetMany(entries map[interface{}]interface{}) {
+	if this.interceptors != nil {
+		for key, val := range entries {
+			this.Set(key, val)
+		}
+		return
+	}
+	for key := range entries {
+		this.mustCheckKey(key)
+	}
+
+	this.writeLock()
+	defer this.lock.Unlock()
+
+	if this.frozen {
+		panic(ErrFrozen)
+	}
+
+	if this.items == nil {
+		this.items = make(map[interface{}]interface{}, len(entries))
+	}
+	for key, val := range entries {
+		this.items[key] = val
+		this.keyAdded(key)
+	}
+	if this.stats != nil {
+		this.stats.sets.Add(uint64(len(entries)))
+	}
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, under a single lock acquisition.
+// Returns keys of inserted entries; entries with already existing keys are left untouched.
+// Panics if any key cannot be used as a map key, before any entry is set unless interceptors are installed, see WithInterceptors.
+// Panics with ErrFrozen if the map is frozen.
+func (this *ConcurrentMap) SetManyIfNotExists(entries map[interface{}]interface{}) []interface{} {
+	if this.interceptors != nil {
+		inserted := make([]interface{}, 0, len(entries))
+		for key, val := range entries {
+			if this.SetIfNotExists(key, val) {
+				inserted = append(inserted, key)
+			}
+		}
+		return inserted
+	}
+	for key := range entries {
+		this.mustCheckKey(key)
+	}
+
+	this.writeLock()
+	defer this.lock.Unlock()
+
+	if this.frozen {
+		panic(ErrFrozen)
+	}
+
+	if this.items == nil {
+		this.items = make(map[interface{}]interface{}, len(entries))
+	}
+	inserted := make([]interface{}, 0, len(entries))
+	for key, val := range entries {
+		if _, ok := this.items[key]; !ok {
+			this.items[key] = val
+			this.keyAdded(key)
+			inserted = append(inserted, key)
+		}
+	}
+	if this.stats != nil {
+		this.stats.sets.Add(uint64(len(inserted)))
+	}
+	return inserted
+}
+
+// Retrieves elements under given keys under a single lock acquisition.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *ConcurrentMap) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	if this.interceptors != nil {
+		found := make(map[interface{}]interface{}, len(keys))
+		missing := []interface{}{}
+		for _, key := range keys {
+			if val, ok := this.Get(key); ok {
+				found[key] = val
+			} else {
+				missing = append(missing, key)
+			}
+		}
+		return found, missing
+	}
+	this.readLock()
+	defer this.lock.RUnlock()
+
+	found := make(map[interface{}]interface{}, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if val, ok := this.items[key]; ok {
+			found[key] = val
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	if this.stats != nil {
+		this.stats.gets.Add(uint64(len(keys)))
+		this.stats.hits.Add(uint64(len(keys) - len(missing)))
+		this.stats.misses.Add(uint64(len(missing)))
+	}
+	return found, missing
+}
+
+// Removes elements under given keys under a single lock acquisition.
+// Panics with ErrFrozen if the map is frozen.
+func (this *ConcurrentMap) RemoveMany(keys []interface{}) {
+	if this.interceptors != nil {
+		for _, key := range keys {
+			this.Remove(key)
+		}
+		return
+	}
+	this.writeLock()
+	defer this.lock.Unlock()
+
+	if this.frozen {
+		panic(ErrFrozen)
+	}
+
+	for _, key := range keys {
+		delete(this.items, key)
+		this.keyRemoved(key)
+	}
+	if this.stats != nil {
+		this.stats.removes.Add(uint64(len(keys)))
+	}
+}
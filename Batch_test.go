@@ -0,0 +1,152 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestSetManyItemsCycle(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias        string
+		Cm               *ConcurrentMap
+		Entries          map[interface{}]interface{}
+		IfNotExists      bool
+		ExpectedInserted []interface{}
+		ExpectedItems    map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "MakeConcurrentCopy and SetMany existing and non-existing keys",
+			Cm:            MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			Entries:       map[interface{}]interface{}{"key2": 321, "key3": 4.56},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 321, "key3": 4.56},
+		},
+		{
+			TestAlias:     "new(ConcurrentMap) and SetMany non-existing keys",
+			Cm:            new(ConcurrentMap),
+			Entries:       map[interface{}]interface{}{"key2": 321, "key3": 4.56},
+			ExpectedItems: map[interface{}]interface{}{"key2": 321, "key3": 4.56},
+		},
+		{
+			TestAlias:        "MakeConcurrentCopy and SetManyIfNotExists existing and non-existing keys",
+			Cm:               MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			Entries:          map[interface{}]interface{}{"key2": 321, "key3": 4.56, "key4": true},
+			IfNotExists:      true,
+			ExpectedInserted: []interface{}{"key3", "key4"},
+			ExpectedItems:    map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56, "key4": true},
+		},
+		{
+			TestAlias:        "new(ConcurrentMap) and SetManyIfNotExists non-existing keys",
+			Cm:               new(ConcurrentMap),
+			Entries:          map[interface{}]interface{}{"key3": 4.56},
+			IfNotExists:      true,
+			ExpectedInserted: []interface{}{"key3"},
+			ExpectedItems:    map[interface{}]interface{}{"key3": 4.56},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Cm
+		entries := testCase.Entries
+		ifNotExists := testCase.IfNotExists
+		expectedInserted := testCase.ExpectedInserted
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			if ifNotExists {
+				actualInserted := cm.SetManyIfNotExists(entries)
+				sort.Slice(actualInserted, func(i, j int) bool { return actualInserted[i].(string) < actualInserted[j].(string) })
+
+				if !(reflect.DeepEqual(actualInserted, expectedInserted)) {
+					t.Errorf("%s :: cm.SetManyIfNotExists(%#v) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, entries, actualInserted, expectedInserted)
+				}
+			} else {
+				cm.SetMany(entries)
+			}
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() after setting %#v returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, entries, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestGetManyRemoveManyCycle(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias       string
+		Cm              *ConcurrentMap
+		Keys            []interface{}
+		ExpectedFound   map[interface{}]interface{}
+		ExpectedMissing []interface{}
+		ExpectedItems   map[interface{}]interface{}
+	}{
+		{
+			TestAlias:       "MakeConcurrentCopy and existing and non-existing keys",
+			Cm:              MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56}),
+			Keys:            []interface{}{"key4", "key2", "key5", "key3"},
+			ExpectedFound:   map[interface{}]interface{}{"key2": 123, "key3": 4.56},
+			ExpectedMissing: []interface{}{"key4", "key5"},
+			ExpectedItems:   map[interface{}]interface{}{"key1": "stringValue"},
+		},
+		{
+			TestAlias:       "new(ConcurrentMap) and non-existing keys",
+			Cm:              new(ConcurrentMap),
+			Keys:            []interface{}{"key1"},
+			ExpectedFound:   map[interface{}]interface{}{},
+			ExpectedMissing: []interface{}{"key1"},
+			ExpectedItems:   map[interface{}]interface{}{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Cm
+		keys := testCase.Keys
+		expectedFound := testCase.ExpectedFound
+		expectedMissing := testCase.ExpectedMissing
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			actualFound, actualMissing := cm.GetMany(keys)
+
+			if !(reflect.DeepEqual(actualFound, expectedFound)) || !(reflect.DeepEqual(actualMissing, expectedMissing)) {
+				t.Errorf("%s :: cm.GetMany(%#v) returned \r\n %#v, %#v \r\n while expected \r\n %#v, %#v ", testAlias, keys, actualFound, actualMissing, expectedFound, expectedMissing)
+			}
+
+			cm.RemoveMany(keys)
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() after cm.RemoveMany(%#v) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, keys, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,139 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Errors of the checked API
+var (
+	// The key is of a type which cannot be used as a map key, e.g. a slice, a map or a func, or it holds such a value.
+	ErrUnhashableKey = errors.New("concurrentmap: unhashable key")
+	// The key is not of the type the map is restricted to by WithKeyType option.
+	ErrKeyType = errors.New("concurrentmap: unexpected key type")
+)
+
+// Retrieves an element from map under given key, same as Get.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key, or the error of a rejecting interceptor.
+func (this *ConcurrentMap) TryGet(key interface{}) (interface{}, bool, error) {
+	if this.interceptors != nil {
+		op := &Operation{Kind: OperationGet, Key: key}
+		err := this.invoke(op)
+		return op.Value, op.Found, err
+	}
+	if err := checkHashable(key); err != nil {
+		return nil, false, err
+	}
+	val, ok := this.get(key)
+	return val, ok, nil
+}
+
+// Sets the given value under the specified key, same as Set.
+// Returns ErrUnhashableKey or ErrKeyType instead of panicking if the key cannot be stored in the map,
+// ErrFrozen if the map is frozen or the error of a rejecting interceptor.
+func (this *ConcurrentMap) TrySet(key interface{}, val interface{}) error {
+	if this.interceptors != nil {
+		return this.invoke(&Operation{Kind: OperationSet, Key: key, Value: val})
+	}
+	if err := this.checkKey(key); err != nil {
+		return err
+	}
+	return this.set(key, val)
+}
+
+// Sets the given value under the specified key if the key didn't exist upon invokation, same as SetIfNotExists.
+// Returns ErrUnhashableKey or ErrKeyType instead of panicking if the key cannot be stored in the map,
+// ErrFrozen if the map is frozen or the error of a rejecting interceptor.
+func (this *ConcurrentMap) TrySetIfNotExists(key interface{}, val interface{}) (bool, error) {
+	if this.interceptors != nil {
+		op := &Operation{Kind: OperationSetIfNotExists, Key: key, Value: val}
+		err := this.invoke(op)
+		return op.Found, err
+	}
+	if err := this.checkKey(key); err != nil {
+		return false, err
+	}
+	return this.setIfNotExists(key, val)
+}
+
+// Removes an element from the map, same as Remove.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key,
+// ErrFrozen if the map is frozen or the error of a rejecting interceptor.
+func (this *ConcurrentMap) TryRemove(key interface{}) error {
+	if this.interceptors != nil {
+		return this.invoke(&Operation{Kind: OperationRemove, Key: key})
+	}
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	return this.remove(key)
+}
+
+// Returns an error if the `key` cannot be stored in the map.
+func (this *ConcurrentMap) checkKey(key interface{}) error {
+	if this.keyType != nil && reflect.TypeOf(key) != this.keyType {
+		return fmt.Errorf("%w: %T while expected %v", ErrKeyType, key, this.keyType)
+	}
+	return checkHashable(key)
+}
+
+// Panics if the `key` cannot be stored in the map, before the map gets locked.
+func (this *ConcurrentMap) mustCheckKey(key interface{}) {
+	if this.keyType == nil {
+		// Unhashable keys make the runtime panic by themselves
+		return
+	}
+	if err := this.checkKey(key); err != nil {
+		panic(err)
+	}
+}
+
+// Returns ErrUnhashableKey if the `key` cannot be used as a key of `map[interface{}]interface{}`.
+func checkHashable(key interface{}) error {
+	switch key.(type) {
+	case nil, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, complex64, complex128, bool:
+		return nil
+	}
+	if !isHashable(reflect.ValueOf(key)) {
+		return fmt.Errorf("%w: %T", ErrUnhashableKey, key)
+	}
+	return nil
+}
+
+// Returns true if the `v` can be compared with ==, inspecting values of interfaces it holds.
+func isHashable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	case reflect.Interface:
+		return v.IsNil() || isHashable(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isHashable(v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isHashable(v.Field(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
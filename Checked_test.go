@@ -0,0 +1,109 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+type hashableKey struct {
+	Name  string
+	Value interface{}
+}
+
+func TestCheckedAPIKeys(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Key           interface{}
+		ExpectedError error
+	}{
+		{
+			TestAlias:     "String key",
+			Key:           "key",
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:     "Nil key",
+			Key:           nil,
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:     "Comparable struct key",
+			Key:           hashableKey{Name: "key", Value: [2]int{1, 2}},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:     "Slice key",
+			Key:           []byte("key"),
+			ExpectedError: ErrUnhashableKey,
+		},
+		{
+			TestAlias:     "Map key",
+			Key:           map[string]int{},
+			ExpectedError: ErrUnhashableKey,
+		},
+		{
+			TestAlias:     "Struct key holding a slice",
+			Key:           hashableKey{Name: "key", Value: []int{1, 2}},
+			ExpectedError: ErrUnhashableKey,
+		},
+		{
+			TestAlias:     "Array key holding a func",
+			Key:           [1]interface{}{func() {}},
+			ExpectedError: ErrUnhashableKey,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		key := testCase.Key
+		expectedError := testCase.ExpectedError
+
+		testFn := func(t *testing.T) {
+
+			cm := New(0)
+
+			if err := cm.TrySet(key, "value"); !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.TrySet(%#v, ...) returned error %v while expected %v ", testAlias, key, err, expectedError)
+			}
+			if _, err := cm.TrySetIfNotExists(key, "value"); !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.TrySetIfNotExists(%#v, ...) returned error %v while expected %v ", testAlias, key, err, expectedError)
+			}
+
+			actualValue, actualOk, err := cm.TryGet(key)
+
+			if !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.TryGet(%#v) returned error %v while expected %v ", testAlias, key, err, expectedError)
+			}
+			if expectedError == nil && (!actualOk || actualValue != "value") {
+				t.Errorf("%s :: cm.TryGet(%#v) after cm.TrySet returned %#v, %v while expected \"value\", true ", testAlias, key, actualValue, actualOk)
+			}
+
+			if err := cm.TryRemove(key); !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.TryRemove(%#v) returned error %v while expected %v ", testAlias, key, err, expectedError)
+			}
+			if actualItems := cm.Items(); !(reflect.DeepEqual(actualItems, map[interface{}]interface{}{})) {
+				t.Errorf("%s :: cm.Items() after cm.TryRemove(%#v) returned %#v while expected an empty map ", testAlias, key, actualItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,90 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "reflect"
+
+// Returns a shallow copy of the map: nested maps, slices and other reference values are shared with the original.
+// The copy keeps options of the original.
+//
+// It is safe to call concurrently with modifications; the copy reflects a consistent snapshot.
+func (this *ConcurrentMap) Clone() *ConcurrentMap {
+	return this.newLike(snapshotOrderedItems(this))
+}
+
+// Returns a deep copy of the map.
+//
+// Nested *ConcurrentMap values are deeply cloned as well as `[]interface{}` and `[]*ConcurrentMap` slices holding them,
+// so the copy can be modified at any depth without affecting the original. Any other values are shared.
+// Returns *CycleError if the map references itself.
+//
+// It is safe to call concurrently with modifications; each nested map is copied from its own consistent snapshot.
+func (this *ConcurrentMap) DeepClone() (*ConcurrentMap, error) {
+	return deepCloneMap(cycleGuard{}, nil, this)
+}
+
+func deepCloneMap(guard cycleGuard, path []interface{}, cm *ConcurrentMap) (*ConcurrentMap, error) {
+	key, err := guard.enterMap(path, cm)
+	if err != nil {
+		return nil, err
+	}
+	defer guard.leave(key)
+
+	items, keys := snapshotOrderedItems(cm)
+	for k, value := range items {
+		if items[k], err = deepCloneValue(guard, appendPath(path, k), value); err != nil {
+			return nil, err
+		}
+	}
+	return cm.newLike(items, keys), nil
+}
+
+func deepCloneValue(guard cycleGuard, path []interface{}, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case *ConcurrentMap:
+		if x == nil {
+			return x, nil
+		}
+		return deepCloneMap(guard, path, x)
+	case []interface{}:
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		sl := make([]interface{}, len(x))
+		for i, j := range x {
+			if sl[i], err = deepCloneValue(guard, appendPath(path, i), j); err != nil {
+				return nil, err
+			}
+		}
+		return sl, nil
+	case []*ConcurrentMap:
+		sl := make([]*ConcurrentMap, len(x))
+		for i, j := range x {
+			if j == nil {
+				continue
+			}
+			cm, err := deepCloneMap(guard, appendPath(path, i), j)
+			if err != nil {
+				return nil, err
+			}
+			sl[i] = cm
+		}
+		return sl, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,111 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestCloneIsolation(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias              string
+		Original               map[interface{}]interface{}
+		Deep                   bool
+		ModifyFn               func(clone *ConcurrentMap)
+		ExpectedOriginalItems  map[interface{}]interface{}
+		ExpectedNestedModified bool
+	}{
+		{
+			TestAlias: "Shallow clone and Set top-level key",
+			Original:  map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{"key": "value"}},
+			Deep:      false,
+			ModifyFn: func(clone *ConcurrentMap) {
+				clone.Set("key1", "otherValue")
+			},
+			ExpectedOriginalItems:  map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{"key": "value"}},
+			ExpectedNestedModified: false,
+		},
+		{
+			TestAlias: "Shallow clone and Set nested key",
+			Original:  map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{"key": "value"}},
+			Deep:      false,
+			ModifyFn: func(clone *ConcurrentMap) {
+				nested, _ := clone.Get("Map")
+				nested.(*ConcurrentMap).Set("key", "otherValue")
+			},
+			ExpectedOriginalItems:  map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{"key": "otherValue"}},
+			ExpectedNestedModified: true,
+		},
+		{
+			TestAlias: "Deep clone and Set nested key",
+			Original:  map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{"key": "value"}},
+			Deep:      true,
+			ModifyFn: func(clone *ConcurrentMap) {
+				nested, _ := clone.Get("Map")
+				nested.(*ConcurrentMap).Set("key", "otherValue")
+			},
+			ExpectedOriginalItems:  map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{"key": "value"}},
+			ExpectedNestedModified: false,
+		},
+		{
+			TestAlias: "Deep clone and Set key of map inside slice",
+			Original:  map[interface{}]interface{}{"Slice": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}},
+			Deep:      true,
+			ModifyFn: func(clone *ConcurrentMap) {
+				sl, _ := clone.Get("Slice")
+				sl.([]interface{})[0].(*ConcurrentMap).Set("key", "otherValue")
+			},
+			ExpectedOriginalItems:  map[interface{}]interface{}{"Slice": []interface{}{map[interface{}]interface{}{"key": "value"}}},
+			ExpectedNestedModified: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		original := MakeRecursivelyConcurrentCopy(testCase.Original)
+		deep := testCase.Deep
+		modifyFn := testCase.ModifyFn
+		expectedOriginalItems := testCase.ExpectedOriginalItems
+
+		testFn := func(t *testing.T) {
+
+			clone := original.Clone()
+			if deep {
+				var err error
+				if clone, err = original.DeepClone(); err != nil {
+					t.Fatalf("%s :: original.DeepClone() returned unexpected error %v ", testAlias, err)
+				}
+			}
+
+			if !clone.Equal(original) {
+				t.Errorf("%s :: clone.Equal(original) returned false right after cloning: \r\n %#v ", testAlias, clone.Items())
+			}
+
+			modifyFn(clone)
+
+			actualOriginalItems, _ := original.ToRecursiveMap()
+
+			if !(reflect.DeepEqual(actualOriginalItems, expectedOriginalItems)) {
+				t.Errorf("%s :: original.ToRecursiveMap() after modifying clone returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualOriginalItems, expectedOriginalItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,221 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Errors of ConvertRecursively
+var (
+	// The value passed to ConvertRecursively is neither a map nor a pointer to a map.
+	ErrNotAMap = errors.New("concurrentmap: value to convert is not a map")
+)
+
+// Represents an option of recursive conversion, see ConvertRecursively.
+type ConversionOption func(*conversionConfig)
+
+type conversionConfig struct {
+	convertMap func(mapType reflect.Type) bool
+	sequences  bool
+	pointers   bool
+}
+
+// Restricts conversion to map types accepted by `fn`. Maps of other types are kept as is and not descended into.
+// The root map is always converted.
+func WithMapFilter(fn func(mapType reflect.Type) bool) ConversionOption {
+	return func(config *conversionConfig) {
+		config.convertMap = fn
+	}
+}
+
+// Keeps slices and arrays as is, so maps inside them are not converted.
+func WithoutSequences() ConversionOption {
+	return func(config *conversionConfig) {
+		config.sequences = false
+	}
+}
+
+// Keeps pointers as is, so maps they point to are not converted.
+func WithoutPointers() ConversionOption {
+	return func(config *conversionConfig) {
+		config.pointers = false
+	}
+}
+
+func newConversionConfig(opts []ConversionOption) *conversionConfig {
+	config := &conversionConfig{
+		convertMap: func(reflect.Type) bool { return true },
+		sequences:  true,
+		pointers:   true,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// Makes Concurrent copy of the map `m` of any type recursively.
+//
+// By default, values are converted as follows:
+//   - maps of any type become *ConcurrentMap, keeping their keys as is;
+//   - slices and arrays of maps become `[]*ConcurrentMap`;
+//   - slices and arrays which may hold maps at any depth (e.g. `[]interface{}`) become `[]interface{}` with converted elements;
+//   - non-nil pointers to values which may hold maps are replaced by their converted targets;
+//   - anything else is kept as is.
+//
+// `opts` restrict which types are converted. `m` may also be a pointer to a map.
+// Returns ErrNotAMap if `m` is not a map and *CycleError if `m` references itself, directly or through nested values.
+func ConvertRecursively(m interface{}, opts ...ConversionOption) (*ConcurrentMap, error) {
+	v := reflect.ValueOf(m)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map {
+		return nil, ErrNotAMap
+	}
+	converter := &converter{config: newConversionConfig(opts), visiting: cycleGuard{}, holdsMaps: make(map[reflect.Type]bool)}
+	return converter.convertMap(nil, v)
+}
+
+type converter struct {
+	config   *conversionConfig
+	visiting cycleGuard
+	// Caches results of mayHoldMaps, which also protects it from recursive types
+	holdsMaps map[reflect.Type]bool
+}
+
+func (this *converter) convertMap(path []interface{}, v reflect.Value) (*ConcurrentMap, error) {
+	if v.IsNil() {
+		return New(0), nil
+	}
+	key, err := this.visiting.enter(path, v)
+	if err != nil {
+		return nil, err
+	}
+	defer this.visiting.leave(key)
+
+	items := make(map[interface{}]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key().Interface()
+		value, err := this.convertValue(appendPath(path, k), iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		items[k] = value
+	}
+	return newConcurrentMap(items), nil
+}
+
+func (this *converter) convertValue(path []interface{}, v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v.Interface(), nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || !this.mayHoldMaps(v.Type()) {
+		return valueInterface(v), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		return this.convertMap(path, v)
+	case reflect.Slice, reflect.Array:
+		return this.convertSequence(path, v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v.Interface(), nil
+		}
+		key, err := this.visiting.enter(path, v)
+		if err != nil {
+			return nil, err
+		}
+		defer this.visiting.leave(key)
+		return this.convertValue(path, v.Elem())
+	}
+	return v.Interface(), nil
+}
+
+func (this *converter) convertSequence(path []interface{}, v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			return v.Interface(), nil
+		}
+		if v.Len() > 0 {
+			key, err := this.visiting.enter(path, v)
+			if err != nil {
+				return nil, err
+			}
+			defer this.visiting.leave(key)
+		}
+	}
+
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Map {
+		sl := make([]*ConcurrentMap, v.Len())
+		for i := range sl {
+			cm, err := this.convertMap(appendPath(path, i), v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			sl[i] = cm
+		}
+		return sl, nil
+	}
+
+	sl := make([]interface{}, v.Len())
+	for i := range sl {
+		value, err := this.convertValue(appendPath(path, i), v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		sl[i] = value
+	}
+	return sl, nil
+}
+
+// Returns true if values of the type `t` are converted according to configuration.
+func (this *converter) mayHoldMaps(t reflect.Type) bool {
+	if holds, ok := this.holdsMaps[t]; ok {
+		return holds
+	}
+	// Recursive types reaching themselves without passing a map or an interface never hold maps
+	this.holdsMaps[t] = false
+
+	holds := false
+	switch t.Kind() {
+	case reflect.Map:
+		holds = this.config.convertMap(t)
+	case reflect.Interface:
+		holds = true
+	case reflect.Slice, reflect.Array:
+		holds = this.config.sequences && this.mayHoldMaps(t.Elem())
+	case reflect.Ptr:
+		holds = this.config.pointers && this.mayHoldMaps(t.Elem())
+	}
+	this.holdsMaps[t] = holds
+	return holds
+}
+
+// Returns underlying value of `v` or nil if `v` is invalid or not exported.
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,140 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestConvertRecursively(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Original      interface{}
+		Options       []ConversionOption
+		ExpectedItems map[interface{}]interface{}
+		ExpectedError error
+	}{
+		{
+			TestAlias:     "Converting map[string]interface{}",
+			Original:      map[string]interface{}{"key1": "stringValue", "key2": 123},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+		},
+		{
+			TestAlias:     "Converting pointer to map",
+			Original:      &map[string]int{"key1": 1},
+			ExpectedItems: map[interface{}]interface{}{"key1": 1},
+		},
+		{
+			TestAlias:     "Converting nested maps of any type",
+			Original:      map[int]interface{}{1: map[string]int{"key": 1}, 2: map[interface{}]interface{}{"key": "value"}},
+			ExpectedItems: map[interface{}]interface{}{1: MakeConcurrentCopy(map[interface{}]interface{}{"key": 1}), 2: MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+		},
+		{
+			TestAlias: "Converting slices and arrays of maps",
+			Original: map[string]interface{}{
+				"Slice": []map[string]interface{}{{"key": "value"}},
+				"Array": [1]map[string]interface{}{{"key": "value"}},
+				"Mixed": []interface{}{map[string]interface{}{"key": "value"}, []map[string]interface{}{{"key": "value"}}, "value"},
+				"Plain": []int{1, 2, 3},
+			},
+			ExpectedItems: map[interface{}]interface{}{
+				"Slice": []*ConcurrentMap{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+				"Array": []*ConcurrentMap{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+				"Mixed": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}), []*ConcurrentMap{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}, "value"},
+				"Plain": []int{1, 2, 3},
+			},
+		},
+		{
+			TestAlias:     "Converting pointers to nested maps",
+			Original:      map[string]interface{}{"Ptr": &map[string]interface{}{"key": "value"}},
+			ExpectedItems: map[interface{}]interface{}{"Ptr": MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+		},
+		{
+			TestAlias:     "WithoutPointers keeps pointers",
+			Original:      map[string]interface{}{"Ptr": &map[string]interface{}{"key": "value"}},
+			Options:       []ConversionOption{WithoutPointers()},
+			ExpectedItems: map[interface{}]interface{}{"Ptr": &map[string]interface{}{"key": "value"}},
+		},
+		{
+			TestAlias:     "WithoutSequences keeps slices",
+			Original:      map[string]interface{}{"Slice": []map[string]interface{}{{"key": "value"}}},
+			Options:       []ConversionOption{WithoutSequences()},
+			ExpectedItems: map[interface{}]interface{}{"Slice": []map[string]interface{}{{"key": "value"}}},
+		},
+		{
+			TestAlias: "WithMapFilter keeps rejected map types",
+			Original:  map[string]interface{}{"Map": map[string]interface{}{"key": "value"}, "IntMap": map[string]int{"key": 1}},
+			Options: []ConversionOption{WithMapFilter(func(mapType reflect.Type) bool {
+				return mapType.Elem().Kind() == reflect.Interface
+			})},
+			ExpectedItems: map[interface{}]interface{}{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}), "IntMap": map[string]int{"key": 1}},
+		},
+		{
+			TestAlias:     "Converting non-map value",
+			Original:      []interface{}{1, 2, 3},
+			ExpectedError: ErrNotAMap,
+		},
+		{
+			TestAlias:     "Converting self-referencing map",
+			Original:      selfReferencingMap(),
+			ExpectedError: &CycleError{Path: []interface{}{"Map", "Self"}},
+		},
+		{
+			TestAlias:     "Converting map shared by siblings",
+			Original:      map[string]interface{}{"Map1": map[string]interface{}{"Shared": sharedMap}, "Map2": map[string]interface{}{"Shared": sharedMap}},
+			ExpectedItems: map[interface{}]interface{}{"Map1": MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Shared": map[interface{}]interface{}{"key": "value"}}), "Map2": MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Shared": map[interface{}]interface{}{"key": "value"}})},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		original := testCase.Original
+		options := testCase.Options
+		expectedItems := testCase.ExpectedItems
+		expectedError := testCase.ExpectedError
+
+		testFn := func(t *testing.T) {
+
+			cm, actualError := ConvertRecursively(original, options...)
+
+			if !(reflect.DeepEqual(actualError, expectedError)) {
+				t.Fatalf("%s :: ConvertRecursively(%#v) returned error \r\n %v \r\n while expected \r\n %v ", testAlias, original, actualError, expectedError)
+			}
+			if expectedError != nil {
+				return
+			}
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: ConvertRecursively(%#v).Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, original, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+var sharedMap = map[string]interface{}{"key": "value"}
+
+func selfReferencingMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	m["Map"] = map[string]interface{}{"Self": m}
+	return m
+}
@@ -0,0 +1,60 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "reflect"
+
+// Represents an error of a recursive operation which has reached a value it is already inside of.
+//
+// Path holds keys of nested maps and int indexes of nested slices, starting from the root, up to the repeated value.
+type CycleError struct {
+	Path []interface{}
+}
+
+// Implements error interface.
+func (this *CycleError) Error() string {
+	return "concurrentmap: cycle detected at " + jsonPointer(this.Path)
+}
+
+// Identifies a map, a slice or a pointer a recursive operation is inside of.
+type visitKey struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// Tracks maps, slices and pointers a recursive operation is currently inside of.
+// It is not safe for concurrent use, every operation has its own guard.
+type cycleGuard map[visitKey]bool
+
+// Marks the `v` as entered at the `path`. Returns *CycleError if the operation is already inside of it.
+// The `v` must be a map, a slice or a pointer; every successful enter must be followed by leave.
+func (this cycleGuard) enter(path []interface{}, v reflect.Value) (visitKey, error) {
+	key := visitKey{typ: v.Type(), ptr: v.Pointer()}
+	if this[key] {
+		return key, &CycleError{Path: path}
+	}
+	this[key] = true
+	return key, nil
+}
+
+// Marks the value identified by the `key` as left.
+func (this cycleGuard) leave(key visitKey) {
+	delete(this, key)
+}
+
+// Enters the `cm`, see enter.
+func (this cycleGuard) enterMap(path []interface{}, cm *ConcurrentMap) (visitKey, error) {
+	return this.enter(path, reflect.ValueOf(cm))
+}
@@ -0,0 +1,144 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Walks the map and every value reachable from it and returns *CycleError reporting the path of the first value
+// found inside of itself. Returns nil if the map does not reference itself.
+//
+// Values are followed the way Fingerprint follows them: through nested *ConcurrentMap and *OrderedMap values, slices,
+// arrays, Go maps, structs, pointers and interfaces. So once it returns nil, none of recursive operations (Diff, DeepClone,
+// ToRecursiveMap, Fingerprint, MarshalJSON, etc) is going to fail with *CycleError. A reported cycle may still be harmless
+// to operations following fewer kinds of values, e.g. ToRecursiveMap does not look into structs.
+// Keys of each map are visited in a stable order, so the same tree always reports the same path.
+//
+// It is safe to call concurrently with modifications; each nested map is inspected by its own consistent snapshot.
+func (this *ConcurrentMap) DetectCycles() error {
+	return detectCycles(cycleGuard{}, nil, this)
+}
+
+func detectCycles(guard cycleGuard, path []interface{}, v interface{}) error {
+	switch x := v.(type) {
+	case *ConcurrentMap:
+		key, err := guard.enterMap(path, x)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		items := snapshotItems(x)
+		for _, k := range sortedKeys(items) {
+			if err := detectCycles(guard, appendPath(path, k), items[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *OrderedMap:
+		if x == nil {
+			return nil
+		}
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		for _, entry := range x.Entries() {
+			if err := detectCycles(guard, appendPath(path, entry.Key), entry.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return detectCyclesValue(guard, path, reflect.ValueOf(v))
+}
+
+// Walks the `v` by reflection, handing nested maps over to detectCycles, so that they are inspected under their lock.
+func detectCyclesValue(guard cycleGuard, path []interface{}, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		switch v.Type() {
+		case concurrentMapPtrType:
+			return detectCycles(guard, path, (*ConcurrentMap)(v.UnsafePointer()))
+		case orderedMapPtrType:
+			return detectCycles(guard, path, (*OrderedMap)(v.UnsafePointer()))
+		}
+		key, err := guard.enter(path, v)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		return detectCyclesValue(guard, path, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return detectCyclesValue(guard, path, v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		key, err := guard.enter(path, v)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		for i := 0; i < v.Len(); i++ {
+			if err := detectCyclesValue(guard, appendPath(path, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := detectCyclesValue(guard, appendPath(path, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		key, err := guard.enter(path, v)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			if err := detectCyclesValue(guard, appendPath(path, fingerprintPathElem(k)), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if err := detectCyclesValue(guard, appendPath(path, v.Type().Field(i).Name), v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
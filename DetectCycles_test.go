@@ -0,0 +1,197 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestDetectCycles(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		CmFn          func() *ConcurrentMap
+		ExpectedError error
+	}{
+		{
+			TestAlias: "Nested maps without cycles",
+			CmFn: func() *ConcurrentMap {
+				return MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key": "value"}}})
+			},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias: "Map shared by siblings",
+			CmFn: func() *ConcurrentMap {
+				shared := MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})
+				return MakeConcurrentCopy(map[interface{}]interface{}{"Map1": shared, "Map2": shared, "Slice": []interface{}{shared, shared}})
+			},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias: "Map inside itself",
+			CmFn: func() *ConcurrentMap {
+				cm := New(0)
+				cm.Set("Self", cm)
+				return cm
+			},
+			ExpectedError: &CycleError{Path: []interface{}{"Self"}},
+		},
+		{
+			TestAlias: "Map inside itself through a chain",
+			CmFn: func() *ConcurrentMap {
+				cm := New(0)
+				nested := New(0)
+				cm.Set("Map", nested)
+				nested.Set("Slice", []interface{}{"value", cm})
+				return cm
+			},
+			ExpectedError: &CycleError{Path: []interface{}{"Map", "Slice", 1}},
+		},
+		{
+			TestAlias: "Slice inside itself",
+			CmFn: func() *ConcurrentMap {
+				sl := []interface{}{nil}
+				sl[0] = sl
+				return MakeConcurrentCopy(map[interface{}]interface{}{"Slice": sl})
+			},
+			ExpectedError: &CycleError{Path: []interface{}{"Slice", 0}},
+		},
+		{
+			TestAlias: "Map inside itself through a Go map",
+			CmFn: func() *ConcurrentMap {
+				cm := New(0)
+				cm.Set("plain", map[string]interface{}{"self": cm})
+				return cm
+			},
+			ExpectedError: &CycleError{Path: []interface{}{"plain", "self"}},
+		},
+		{
+			TestAlias: "Map inside itself through a struct pointer",
+			CmFn: func() *ConcurrentMap {
+				cm := New(0)
+				cm.Set("Holder", &equalHolder{Map: cm})
+				return cm
+			},
+			ExpectedError: &CycleError{Path: []interface{}{"Holder", "Map"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.CmFn()
+		expectedError := testCase.ExpectedError
+
+		testFn := func(t *testing.T) {
+
+			actualError := cm.DetectCycles()
+			_, fingerprintError := cm.Fingerprint()
+
+			if (actualError == nil) != (fingerprintError == nil) {
+				t.Errorf("%s :: cm.DetectCycles() returned %v while cm.Fingerprint() returned %v ", testAlias, actualError, fingerprintError)
+			}
+
+			if !(reflect.DeepEqual(actualError, expectedError)) {
+				t.Errorf("%s :: cm.DetectCycles() returned \r\n %v \r\n while expected \r\n %v ", testAlias, actualError, expectedError)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestRecursiveOperationsOnSelfReferencingMap(t *testing.T) {
+
+	newSelfReferencingMap := func() *ConcurrentMap {
+		cm := MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})
+		cm.Set("Self", cm)
+		return cm
+	}
+
+	testCases := []struct {
+		TestAlias string
+		OpFn      func(cm *ConcurrentMap) error
+	}{
+		{
+			TestAlias: "Diff",
+			OpFn: func(cm *ConcurrentMap) error {
+				_, err := Diff(cm, newSelfReferencingMap())
+				return err
+			},
+		},
+		{
+			TestAlias: "DeepClone",
+			OpFn: func(cm *ConcurrentMap) error {
+				_, err := cm.DeepClone()
+				return err
+			},
+		},
+		{
+			TestAlias: "ToRecursiveMap",
+			OpFn: func(cm *ConcurrentMap) error {
+				_, err := cm.ToRecursiveMap()
+				return err
+			},
+		},
+		{
+			TestAlias: "ToRecursiveStringMap",
+			OpFn: func(cm *ConcurrentMap) error {
+				_, err := cm.ToRecursiveStringMap()
+				return err
+			},
+		},
+		{
+			TestAlias: "Fingerprint",
+			OpFn: func(cm *ConcurrentMap) error {
+				_, err := cm.Fingerprint()
+				return err
+			},
+		},
+		{
+			TestAlias: "JSONPatch",
+			OpFn: func(cm *ConcurrentMap) error {
+				_, err := Changes{{Type: ChangeAdded, Path: []interface{}{"Map"}, NewValue: cm}}.JSONPatch()
+				return err
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		opFn := testCase.OpFn
+
+		testFn := func(t *testing.T) {
+
+			actualError := opFn(newSelfReferencingMap())
+
+			if _, ok := actualError.(*CycleError); !ok {
+				t.Errorf("%s :: returned error \r\n %#v \r\n while expected *CycleError ", testAlias, actualError)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+	a, b := newSelfReferencingMap(), newSelfReferencingMap()
+	if !a.Equal(b) {
+		t.Errorf("a.Equal(b) returned false for equally built self-referencing maps ")
+	}
+	b.Set("key", "otherValue")
+	if a.Equal(b) {
+		t.Errorf("a.Equal(b) returned true for different self-referencing maps ")
+	}
+}
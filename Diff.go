@@ -0,0 +1,311 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Represents the kind of a single Change reported by Diff.
+type ChangeType int
+
+// Kinds of changes
+const (
+	// The path exists only in the new version.
+	ChangeAdded ChangeType = iota
+	// The path exists only in the old version.
+	ChangeRemoved
+	// The path exists in both versions, but holds different values.
+	ChangeModified
+)
+
+// Returns short human-readable name of the change type.
+func (this ChangeType) String() string {
+	switch this {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return fmt.Sprintf("ChangeType(%d)", int(this))
+}
+
+// Represents a single difference between two versions of a (recursive) ConcurrentMap.
+//
+// Path holds keys of nested maps and int indexes of nested slices, starting from the root map.
+// OldValue is nil for ChangeAdded and NewValue is nil for ChangeRemoved.
+type Change struct {
+	Type     ChangeType
+	Path     []interface{}
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// Represents ordered list of changes as returned by Diff.
+type Changes []Change
+
+// Structurally compares `a` and `b` and returns the list of changes turning `a` into `b`.
+//
+// It walks nested *ConcurrentMap values as well as slices and arrays, so only the deepest differing paths are reported.
+// Keys of each map are visited in a stable order, so the same pair of versions always produces the same list.
+// Elements which exist beyond the common length of two slices are reported as added or removed by index,
+// removals being listed from the highest index down, so the result can be applied sequentially (e.g. as JSON Patch).
+//
+// Nil `a` or `b` is treated as an empty map. Returns *CycleError if either version references itself.
+// It is safe to call concurrently with modifications of either map, but each nested map is snapshotted independently.
+func Diff(a, b *ConcurrentMap) (Changes, error) {
+	differ := &differ{changes: Changes{}, oldGuard: cycleGuard{}, newGuard: cycleGuard{}}
+	if err := differ.diffMaps(nil, a, b); err != nil {
+		return nil, err
+	}
+	return differ.changes, nil
+}
+
+// Returns Items() of the `cm` or an empty map if `cm` is nil.
+func snapshotItems(cm *ConcurrentMap) map[interface{}]interface{} {
+	if cm == nil {
+		return map[interface{}]interface{}{}
+	}
+	return cm.Items()
+}
+
+type differ struct {
+	changes  Changes
+	oldGuard cycleGuard
+	newGuard cycleGuard
+}
+
+func (this *differ) add(change Change) {
+	this.changes = append(this.changes, change)
+}
+
+func (this *differ) diffMaps(path []interface{}, oldCm, newCm *ConcurrentMap) error {
+	oldKey, err := this.oldGuard.enterMap(path, oldCm)
+	if err != nil {
+		return err
+	}
+	defer this.oldGuard.leave(oldKey)
+	newKey, err := this.newGuard.enterMap(path, newCm)
+	if err != nil {
+		return err
+	}
+	defer this.newGuard.leave(newKey)
+
+	oldItems, newItems := snapshotItems(oldCm), snapshotItems(newCm)
+	for _, key := range sortedKeys(oldItems) {
+		oldValue := oldItems[key]
+		newValue, ok := newItems[key]
+		if !ok {
+			this.add(Change{Type: ChangeRemoved, Path: appendPath(path, key), OldValue: oldValue})
+			continue
+		}
+		if err := this.diffValues(appendPath(path, key), oldValue, newValue); err != nil {
+			return err
+		}
+	}
+	for _, key := range sortedKeys(newItems) {
+		if _, ok := oldItems[key]; !ok {
+			this.add(Change{Type: ChangeAdded, Path: appendPath(path, key), NewValue: newItems[key]})
+		}
+	}
+	return nil
+}
+
+func (this *differ) diffValues(path []interface{}, oldValue, newValue interface{}) error {
+	oldCm, oldIsCm := oldValue.(*ConcurrentMap)
+	newCm, newIsCm := newValue.(*ConcurrentMap)
+	if oldIsCm && newIsCm {
+		if oldCm != newCm {
+			return this.diffMaps(path, oldCm, newCm)
+		}
+		return nil
+	}
+
+	if isSequence(oldValue) && isSequence(newValue) {
+		return this.diffSequences(path, reflect.ValueOf(oldValue), reflect.ValueOf(newValue))
+	}
+
+	if !(equality{}).valuesEqual(oldValue, newValue) {
+		this.add(Change{Type: ChangeModified, Path: path, OldValue: oldValue, NewValue: newValue})
+	}
+	return nil
+}
+
+func (this *differ) diffSequences(path []interface{}, oldSeq, newSeq reflect.Value) error {
+	if oldSeq.Kind() == reflect.Slice {
+		oldKey, err := this.oldGuard.enter(path, oldSeq)
+		if err != nil {
+			return err
+		}
+		defer this.oldGuard.leave(oldKey)
+	}
+	if newSeq.Kind() == reflect.Slice {
+		newKey, err := this.newGuard.enter(path, newSeq)
+		if err != nil {
+			return err
+		}
+		defer this.newGuard.leave(newKey)
+	}
+
+	common := oldSeq.Len()
+	if newSeq.Len() < common {
+		common = newSeq.Len()
+	}
+	for i := 0; i < common; i++ {
+		if err := this.diffValues(appendPath(path, i), oldSeq.Index(i).Interface(), newSeq.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	for i := oldSeq.Len() - 1; i >= common; i-- {
+		this.add(Change{Type: ChangeRemoved, Path: appendPath(path, i), OldValue: oldSeq.Index(i).Interface()})
+	}
+	for i := common; i < newSeq.Len(); i++ {
+		this.add(Change{Type: ChangeAdded, Path: appendPath(path, i), NewValue: newSeq.Index(i).Interface()})
+	}
+	return nil
+}
+
+// Returns true if `v` is a slice or an array.
+func isSequence(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	kind := reflect.TypeOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// Returns a new path, so that sibling paths never share the underlying array.
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	x := make([]interface{}, len(path), len(path)+1)
+	copy(x, path)
+	return append(x, elem)
+}
+
+// Returns keys of the `m` in a stable order: by their printed value and then by type name.
+func sortedKeys(m map[interface{}]interface{}) []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		si, sj := fmt.Sprint(keys[i]), fmt.Sprint(keys[j])
+		if si != sj {
+			return si < sj
+		}
+		return fmt.Sprintf("%T", keys[i]) < fmt.Sprintf("%T", keys[j])
+	})
+	return keys
+}
+
+// Represents a single operation of [JSON Patch](https://tools.ietf.org/html/rfc6902).
+// Value is a pointer, so that `null` values are rendered while `remove` operations have no value at all.
+type jsonPatchOperation struct {
+	Op    string       `json:"op"`
+	Path  string       `json:"path"`
+	Value *interface{} `json:"value,omitempty"`
+}
+
+// Renders changes as [JSON Patch](https://tools.ietf.org/html/rfc6902) document.
+//
+// Added, removed and modified paths become `add`, `remove` and `replace` operations respectively.
+// Non-string keys are rendered with fmt.Sprint and nested *ConcurrentMap values are rendered as JSON objects.
+// Returns *CycleError if any value references itself, and an error if several changes render to the same path,
+// e.g. for keys `1` and `"1"`, since such a patch could not be applied.
+func (this Changes) JSONPatch() ([]byte, error) {
+	operations := make([]jsonPatchOperation, 0, len(this))
+	paths := make(map[string]bool, len(this))
+	for _, change := range this {
+		operation := jsonPatchOperation{Path: jsonPointer(change.Path)}
+		if paths[operation.Path] {
+			return nil, fmt.Errorf("concurrentmap: several changes at %s, keys collide when rendered", operation.Path)
+		}
+		paths[operation.Path] = true
+		switch change.Type {
+		case ChangeAdded:
+			value, err := toRecursiveStringValue(cycleGuard{}, change.Path, change.NewValue)
+			if err != nil {
+				return nil, err
+			}
+			operation.Op = "add"
+			operation.Value = &value
+		case ChangeRemoved:
+			operation.Op = "remove"
+		case ChangeModified:
+			value, err := toRecursiveStringValue(cycleGuard{}, change.Path, change.NewValue)
+			if err != nil {
+				return nil, err
+			}
+			operation.Op = "replace"
+			operation.Value = &value
+		default:
+			return nil, fmt.Errorf("concurrentmap: unknown change type %v at %s", change.Type, operation.Path)
+		}
+		operations = append(operations, operation)
+	}
+	return json.Marshal(operations)
+}
+
+// Renders changes as human-readable text, one change per line, marking
+// changed, added and removed values respectively:
+//
+//	~ /path: old -> new
+//	+ /path: new
+//	- /path: old
+func (this Changes) String() string {
+	var buf bytes.Buffer
+	for _, change := range this {
+		path := jsonPointer(change.Path)
+		switch change.Type {
+		case ChangeAdded:
+			fmt.Fprintf(&buf, "+ %s: %s\n", path, formatValue(change.NewValue))
+		case ChangeRemoved:
+			fmt.Fprintf(&buf, "- %s: %s\n", path, formatValue(change.OldValue))
+		default:
+			fmt.Fprintf(&buf, "~ %s: %s -> %s\n", path, formatValue(change.OldValue), formatValue(change.NewValue))
+		}
+	}
+	return buf.String()
+}
+
+// Renders path as [JSON Pointer](https://tools.ietf.org/html/rfc6901).
+func jsonPointer(path []interface{}) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	var buf bytes.Buffer
+	for _, elem := range path {
+		buf.WriteByte('/')
+		buf.WriteString(escaper.Replace(stringKey(elem)))
+	}
+	return buf.String()
+}
+
+// Renders value as JSON if possible, falling back to fmt formatting (e.g. for self-referencing values).
+func formatValue(v interface{}) string {
+	x, err := toRecursiveStringValue(cycleGuard{}, nil, v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	data, err := json.Marshal(x)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
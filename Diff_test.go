@@ -0,0 +1,198 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestDiff(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias       string
+		A               *ConcurrentMap
+		B               *ConcurrentMap
+		ExpectedChanges Changes
+	}{
+		{
+			TestAlias:       "Equal flat maps",
+			A:               MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			B:               MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			ExpectedChanges: Changes{},
+		},
+		{
+			TestAlias: "Added, removed and modified keys of flat maps",
+			A:         MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			B:         MakeConcurrentCopy(map[interface{}]interface{}{"key2": 321, "key3": 4.56}),
+			ExpectedChanges: Changes{
+				{Type: ChangeRemoved, Path: []interface{}{"key1"}, OldValue: "stringValue"},
+				{Type: ChangeModified, Path: []interface{}{"key2"}, OldValue: 123, NewValue: 321},
+				{Type: ChangeAdded, Path: []interface{}{"key3"}, NewValue: 4.56},
+			},
+		},
+		{
+			TestAlias:       "Equal maps nested in Go maps",
+			A:               MakeConcurrentCopy(map[interface{}]interface{}{"Plain": map[string]interface{}{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key": 1})}}),
+			B:               MakeConcurrentCopy(map[interface{}]interface{}{"Plain": map[string]interface{}{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key": 1})}}),
+			ExpectedChanges: Changes{},
+		},
+		{
+			TestAlias: "Modified key of nested map",
+			A:         MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue", "key2": 123}}),
+			B:         MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue", "key2": 321}}),
+			ExpectedChanges: Changes{
+				{Type: ChangeModified, Path: []interface{}{"Map", "key2"}, OldValue: 123, NewValue: 321},
+			},
+		},
+		{
+			TestAlias: "Nested map replaced by scalar",
+			A:         MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue"}}),
+			B:         MakeConcurrentCopy(map[interface{}]interface{}{"Map": "stringValue"}),
+			ExpectedChanges: Changes{
+				{Type: ChangeModified, Path: []interface{}{"Map"}, OldValue: MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue"}), NewValue: "stringValue"},
+			},
+		},
+		{
+			TestAlias: "Maps inside slices",
+			A: MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{
+				MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}),
+				MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}),
+			}}),
+			B: MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{
+				MakeConcurrentCopy(map[interface{}]interface{}{"key": "otherValue"}),
+			}}),
+			ExpectedChanges: Changes{
+				{Type: ChangeModified, Path: []interface{}{"key", 0, "key"}, OldValue: "value", NewValue: "otherValue"},
+				{Type: ChangeRemoved, Path: []interface{}{"key", 1}, OldValue: MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+			},
+		},
+		{
+			TestAlias: "Shrinking slice removes from the tail",
+			A:         MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{1, 2, 3}}),
+			B:         MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{1}}),
+			ExpectedChanges: Changes{
+				{Type: ChangeRemoved, Path: []interface{}{"key", 2}, OldValue: 3},
+				{Type: ChangeRemoved, Path: []interface{}{"key", 1}, OldValue: 2},
+			},
+		},
+		{
+			TestAlias: "Growing slice appends to the tail",
+			A:         MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{1}}),
+			B:         MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{1, 2, 3}}),
+			ExpectedChanges: Changes{
+				{Type: ChangeAdded, Path: []interface{}{"key", 1}, NewValue: 2},
+				{Type: ChangeAdded, Path: []interface{}{"key", 2}, NewValue: 3},
+			},
+		},
+		{
+			TestAlias: "Nil maps",
+			A:         nil,
+			B:         MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}),
+			ExpectedChanges: Changes{
+				{Type: ChangeAdded, Path: []interface{}{"key"}, NewValue: "value"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		a := testCase.A
+		b := testCase.B
+		expectedChanges := testCase.ExpectedChanges
+
+		testFn := func(t *testing.T) {
+
+			actualChanges, actualError := Diff(a, b)
+
+			if actualError != nil {
+				t.Errorf("%s :: Diff(a, b) returned unexpected error %v ", testAlias, actualError)
+			}
+			if !(reflect.DeepEqual(actualChanges, expectedChanges)) {
+				t.Errorf("%s :: Diff(a, b) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualChanges, expectedChanges)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestChangesRenderers(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias         string
+		Changes           Changes
+		ExpectedJSONPatch string
+		ExpectedText      string
+	}{
+		{
+			TestAlias:         "No changes",
+			Changes:           Changes{},
+			ExpectedJSONPatch: `[]`,
+			ExpectedText:      ``,
+		},
+		{
+			TestAlias: "All kinds of changes",
+			Changes: Changes{
+				{Type: ChangeRemoved, Path: []interface{}{"key1"}, OldValue: "stringValue"},
+				{Type: ChangeModified, Path: []interface{}{"Map", "a/b~c"}, OldValue: 123, NewValue: nil},
+				{Type: ChangeAdded, Path: []interface{}{"key", 0}, NewValue: MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+			},
+			ExpectedJSONPatch: `[{"op":"remove","path":"/key1"},{"op":"replace","path":"/Map/a~1b~0c","value":null},{"op":"add","path":"/key/0","value":{"key":"value"}}]`,
+			ExpectedText:      "- /key1: \"stringValue\"\n~ /Map/a~1b~0c: 123 -> null\n+ /key/0: {\"key\":\"value\"}\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		changes := testCase.Changes
+		expectedJSONPatch := testCase.ExpectedJSONPatch
+		expectedText := testCase.ExpectedText
+
+		testFn := func(t *testing.T) {
+
+			actualJSONPatch, actualError := changes.JSONPatch()
+
+			if actualError != nil {
+				t.Errorf("%s :: changes.JSONPatch() returned unexpected error %v ", testAlias, actualError)
+			}
+			if string(actualJSONPatch) != expectedJSONPatch {
+				t.Errorf("%s :: changes.JSONPatch() returned \r\n %s \r\n while expected \r\n %s ", testAlias, actualJSONPatch, expectedJSONPatch)
+			}
+
+			actualText := changes.String()
+
+			if actualText != expectedText {
+				t.Errorf("%s :: changes.String() returned \r\n %q \r\n while expected \r\n %q ", testAlias, actualText, expectedText)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestJSONPatchCollidingPaths(t *testing.T) {
+
+	changes, err := Diff(MakeConcurrentCopy(map[interface{}]interface{}{1: "a", "1": "b"}), New(0))
+	if err != nil {
+		t.Fatalf("Diff(...) returned unexpected error %v ", err)
+	}
+	if _, err := changes.JSONPatch(); err == nil {
+		t.Errorf("changes.JSONPatch() returned no error for keys 1 and \"1\" both rendered as /1 ")
+	}
+
+}
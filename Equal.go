@@ -0,0 +1,205 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "reflect"
+
+// Returns true if the map and `other` hold equal content.
+//
+// Nested *ConcurrentMap values are compared by content recursively, slices and arrays are compared element by element,
+// any other values are compared by the rules of reflect.DeepEqual. Maps nested deeper, e.g. inside structs, pointers or
+// Go maps, are compared by content as well, so unlike reflect.DeepEqual it never inspects their internal lock or items
+// without holding it. Nested *OrderedMap values are compared by their entries in order.
+// Self-referencing maps are handled the same way reflect.DeepEqual handles cyclic values:
+// a pair of maps reached again while being compared is considered equal, so the comparison always terminates.
+//
+// It is safe to call concurrently with modifications of either map.
+// Each map is compared by its own consistent snapshot, taken one at a time, so two maps never get locked simultaneously
+// and concurrent `a.Equal(b)` and `b.Equal(a)` cannot deadlock.
+// Nil maps are equal to each other and to empty maps.
+func (this *ConcurrentMap) Equal(other *ConcurrentMap) bool {
+	return equality{}.mapsEqual(this, other)
+}
+
+// Holds pairs of maps and slices which are already being compared.
+type equality map[[2]visitKey]bool
+
+// Marks the pair of `a` and `b` as being compared. Returns false if it is already being compared.
+func (this equality) visit(a, b reflect.Value) bool {
+	pair := [2]visitKey{{typ: a.Type(), ptr: a.Pointer()}, {typ: b.Type(), ptr: b.Pointer()}}
+	if this[pair] {
+		return false
+	}
+	this[pair] = true
+	return true
+}
+
+func (this equality) mapsEqual(a, b *ConcurrentMap) bool {
+	if a == b || !this.visit(reflect.ValueOf(a), reflect.ValueOf(b)) {
+		return true
+	}
+
+	aItems, bItems := snapshotItems(a), snapshotItems(b)
+	if len(aItems) != len(bItems) {
+		return false
+	}
+	for key, aValue := range aItems {
+		bValue, ok := bItems[key]
+		if !ok || !this.valuesEqual(aValue, bValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this equality) valuesEqual(a, b interface{}) bool {
+	aCm, aIsCm := a.(*ConcurrentMap)
+	bCm, bIsCm := b.(*ConcurrentMap)
+	if aIsCm || bIsCm {
+		return aIsCm && bIsCm && this.mapsEqual(aCm, bCm)
+	}
+
+	if isSequence(a) && isSequence(b) {
+		aSeq, bSeq := reflect.ValueOf(a), reflect.ValueOf(b)
+		if aSeq.Type() != bSeq.Type() || aSeq.Len() != bSeq.Len() {
+			return false
+		}
+		if aSeq.Kind() == reflect.Slice && !this.visit(aSeq, bSeq) {
+			return true
+		}
+		for i := 0; i < aSeq.Len(); i++ {
+			if !this.valuesEqual(aSeq.Index(i).Interface(), bSeq.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return this.deepEqual(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func (this equality) orderedMapsEqual(a, b *OrderedMap) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	if !this.visit(reflect.ValueOf(a), reflect.ValueOf(b)) {
+		return true
+	}
+
+	aEntries, bEntries := a.Entries(), b.Entries()
+	if len(aEntries) != len(bEntries) {
+		return false
+	}
+	for i, aEntry := range aEntries {
+		if aEntry.Key != bEntries[i].Key || !this.valuesEqual(aEntry.Value, bEntries[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compares `a` and `b` by the rules of reflect.DeepEqual, except that *ConcurrentMap values are compared by content.
+func (this equality) deepEqual(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !this.deepEqual(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		if a.UnsafePointer() == b.UnsafePointer() || !this.visit(a, b) {
+			return true
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !this.deepEqual(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		if a.UnsafePointer() == b.UnsafePointer() || !this.visit(a, b) {
+			return true
+		}
+		for iter := a.MapRange(); iter.Next(); {
+			bValue := b.MapIndex(iter.Key())
+			if !bValue.IsValid() || !this.deepEqual(iter.Value(), bValue) {
+				return false
+			}
+		}
+		return true
+	case reflect.Pointer:
+		// Maps may be reached through an unexported field, where Interface is not allowed
+		switch a.Type() {
+		case concurrentMapPtrType:
+			return this.mapsEqual((*ConcurrentMap)(a.UnsafePointer()), (*ConcurrentMap)(b.UnsafePointer()))
+		case orderedMapPtrType:
+			return this.orderedMapsEqual((*OrderedMap)(a.UnsafePointer()), (*OrderedMap)(b.UnsafePointer()))
+		}
+		if a.UnsafePointer() == b.UnsafePointer() {
+			return true
+		}
+		if a.IsNil() || b.IsNil() || !this.visit(a, b) {
+			return a.IsNil() == b.IsNil()
+		}
+		return this.deepEqual(a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return this.deepEqual(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !this.deepEqual(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		// Functions are deeply equal only if both are nil
+		return a.IsNil() && b.IsNil()
+	}
+	// Channels and unsafe pointers are deeply equal only when identical
+	return a.Pointer() == b.Pointer()
+}
@@ -0,0 +1,205 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestEqual(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		A             *ConcurrentMap
+		B             *ConcurrentMap
+		ExpectedEqual bool
+	}{
+		{
+			TestAlias:     "Equal flat maps",
+			A:             MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			B:             MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			ExpectedEqual: true,
+		},
+		{
+			TestAlias:     "Different values",
+			A:             MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			B:             MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 321}),
+			ExpectedEqual: false,
+		},
+		{
+			TestAlias:     "Different keys",
+			A:             MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue"}),
+			B:             MakeConcurrentCopy(map[interface{}]interface{}{"key2": "stringValue"}),
+			ExpectedEqual: false,
+		},
+		{
+			TestAlias:     "Equal nested maps",
+			A:             MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue", "key2": 123}}),
+			B:             MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue", "key2": 123}}),
+			ExpectedEqual: true,
+		},
+		{
+			TestAlias:     "Different nested maps",
+			A:             MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue"}}),
+			B:             MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "otherValue"}}),
+			ExpectedEqual: false,
+		},
+		{
+			TestAlias:     "Nested map vs plain map",
+			A:             MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue"}}),
+			B:             MakeConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue"}}),
+			ExpectedEqual: false,
+		},
+		{
+			TestAlias:     "Equal maps inside slices",
+			A:             MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}}),
+			B:             MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}}),
+			ExpectedEqual: true,
+		},
+		{
+			TestAlias:     "new(ConcurrentMap) vs New(0)",
+			A:             new(ConcurrentMap),
+			B:             New(0),
+			ExpectedEqual: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		a := testCase.A
+		b := testCase.B
+		expectedEqual := testCase.ExpectedEqual
+
+		testFn := func(t *testing.T) {
+
+			actualEqual := a.Equal(b)
+
+			if actualEqual != expectedEqual {
+				t.Errorf("%s :: a.Equal(b) returned %v while expected %v ", testAlias, actualEqual, expectedEqual)
+			}
+
+			actualEqual = b.Equal(a)
+
+			if actualEqual != expectedEqual {
+				t.Errorf("%s :: b.Equal(a) returned %v while expected %v ", testAlias, actualEqual, expectedEqual)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestEqualConcurrently(t *testing.T) {
+
+	a := MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key": "value"}})
+	b := MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key": "value"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				a.Equal(b)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				b.Equal(a)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				a.Set(i, n)
+				b.Set(i, n)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !a.Equal(b) {
+		t.Errorf("a.Equal(b) returned false after equal concurrent modifications: \r\n %#v \r\n %#v ", a.Items(), b.Items())
+	}
+}
+
+type equalHolder struct {
+	Map *ConcurrentMap
+}
+
+func TestEqualNestedConcurrently(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias string
+		// Wraps the map into a value which is not a map value or a slice itself
+		WrapFn func(cm *ConcurrentMap) interface{}
+	}{
+		{
+			TestAlias: "Map inside of a struct",
+			WrapFn:    func(cm *ConcurrentMap) interface{} { return equalHolder{Map: cm} },
+		},
+		{
+			TestAlias: "Map inside of a Go map",
+			WrapFn:    func(cm *ConcurrentMap) interface{} { return map[string]interface{}{"Map": cm} },
+		},
+		{
+			TestAlias: "Map behind a pointer",
+			WrapFn:    func(cm *ConcurrentMap) interface{} { return &equalHolder{Map: cm} },
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		wrapFn := testCase.WrapFn
+
+		testFn := func(t *testing.T) {
+
+			aInner, bInner := New(0), New(0)
+			a := MakeConcurrentCopy(map[interface{}]interface{}{"Holder": wrapFn(aInner)})
+			b := MakeConcurrentCopy(map[interface{}]interface{}{"Holder": wrapFn(bInner)})
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 1000; n++ {
+					aInner.Set(n%10, n)
+					bInner.Set(n%10, n)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 1000; n++ {
+					a.Equal(b)
+				}
+			}()
+			wg.Wait()
+
+			if !a.Equal(b) {
+				t.Errorf("%s :: a.Equal(b) returned false for maps holding equal nested maps ", testAlias)
+			}
+			bInner.Set("extra", true)
+			if a.Equal(b) {
+				t.Errorf("%s :: a.Equal(b) returned true for maps holding different nested maps ", testAlias)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,307 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Type markers of the canonical encoding used by Fingerprint
+const (
+	fingerprintNil      = 'n'
+	fingerprintMap      = 'm'
+	fingerprintSequence = 'l'
+	fingerprintString   = 's'
+	fingerprintOther    = 'v'
+	fingerprintPresent  = 'p'
+	fingerprintOrdered  = 'o'
+)
+
+// Returns a stable hex-encoded SHA-256 hash of the map content, suitable for change detection and ETags.
+//
+// Maps with equal content (see Equal) produce the same fingerprint regardless of insertion order or of the way
+// they were built. Values are hashed by the rules Equal compares them with: nested maps by content, slices and arrays
+// by their type and elements, nested *OrderedMap values by their entries in order, and other values by their dynamic
+// type and structure, following pointers the way reflect.DeepEqual does. Channels and unsafe pointers are compared by identity, so fingerprints of maps holding them
+// are only stable within the process. Values reflect.DeepEqual never finds equal, such as NaN or non-nil functions,
+// may still share a fingerprint.
+//
+// Returns *CycleError if the map or any value inside of it references itself.
+//
+// It is safe to call concurrently with modifications; each nested map is hashed by its own consistent snapshot.
+func (this *ConcurrentMap) Fingerprint() (string, error) {
+	var buf bytes.Buffer
+	if err := writeFingerprintValue(&buf, cycleGuard{}, nil, this); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Writes canonical, length-prefixed encoding of the `v` into the `buf`.
+func writeFingerprintValue(buf *bytes.Buffer, guard cycleGuard, path []interface{}, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		buf.WriteByte(fingerprintNil)
+	case *ConcurrentMap:
+		key, err := guard.enterMap(path, x)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		items := snapshotItems(x)
+		entries := make([][]byte, 0, len(items))
+		for k, value := range items {
+			var entry bytes.Buffer
+			if err := writeFingerprintValue(&entry, guard, path, k); err != nil {
+				return err
+			}
+			if err := writeFingerprintValue(&entry, guard, appendPath(path, k), value); err != nil {
+				return err
+			}
+			entries = append(entries, entry.Bytes())
+		}
+		// Map iteration order is random, so entries are ordered by their own encoding
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i], entries[j]) < 0 })
+
+		buf.WriteByte(fingerprintMap)
+		writeFingerprintLength(buf, len(entries))
+		for _, entry := range entries {
+			buf.Write(entry)
+		}
+	case *OrderedMap:
+		buf.WriteByte(fingerprintOrdered)
+		if x == nil {
+			buf.WriteByte(fingerprintNil)
+			return nil
+		}
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		entries := x.Entries()
+		buf.WriteByte(fingerprintPresent)
+		writeFingerprintLength(buf, len(entries))
+		for _, entry := range entries {
+			if err := writeFingerprintValue(buf, guard, path, entry.Key); err != nil {
+				return err
+			}
+			if err := writeFingerprintValue(buf, guard, appendPath(path, entry.Key), entry.Value); err != nil {
+				return err
+			}
+		}
+	case string:
+		buf.WriteByte(fingerprintString)
+		writeFingerprintString(buf, x)
+	default:
+		if isSequence(x) {
+			seq := reflect.ValueOf(x)
+			if seq.Kind() == reflect.Slice {
+				key, err := guard.enter(path, seq)
+				if err != nil {
+					return err
+				}
+				defer guard.leave(key)
+			}
+
+			buf.WriteByte(fingerprintSequence)
+			writeFingerprintString(buf, seq.Type().String())
+			writeFingerprintLength(buf, seq.Len())
+			for i := 0; i < seq.Len(); i++ {
+				if err := writeFingerprintValue(buf, guard, appendPath(path, i), seq.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		value := reflect.ValueOf(x)
+		buf.WriteByte(fingerprintOther)
+		writeFingerprintString(buf, value.Type().String())
+		return writeFingerprintReflect(buf, guard, path, value)
+	}
+	return nil
+}
+
+// Writes canonical encoding of the `v` which tells apart exactly what reflect.DeepEqual does:
+// pointers are followed, map entries are ordered by their own encoding and nil slices and maps differ from empty ones.
+// Nested *ConcurrentMap values are encoded by content, see writeFingerprintValue.
+func writeFingerprintReflect(buf *bytes.Buffer, guard cycleGuard, path []interface{}, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeFingerprintLength(buf, 1)
+		} else {
+			writeFingerprintLength(buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeFingerprintUint(buf, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeFingerprintUint(buf, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFingerprintFloat(buf, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFingerprintFloat(buf, real(c))
+		writeFingerprintFloat(buf, imag(c))
+	case reflect.String:
+		writeFingerprintString(buf, v.String())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := writeFingerprintReflect(buf, guard, appendPath(path, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteByte(fingerprintNil)
+			return nil
+		}
+		key, err := guard.enter(path, v)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		buf.WriteByte(fingerprintPresent)
+		writeFingerprintLength(buf, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := writeFingerprintReflect(buf, guard, appendPath(path, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(fingerprintNil)
+			return nil
+		}
+		key, err := guard.enter(path, v)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		entries := make([][]byte, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			var entry bytes.Buffer
+			if err := writeFingerprintReflect(&entry, guard, path, iter.Key()); err != nil {
+				return err
+			}
+			if err := writeFingerprintReflect(&entry, guard, appendPath(path, fingerprintPathElem(iter.Key())), iter.Value()); err != nil {
+				return err
+			}
+			entries = append(entries, entry.Bytes())
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i], entries[j]) < 0 })
+
+		buf.WriteByte(fingerprintPresent)
+		writeFingerprintLength(buf, len(entries))
+		for _, entry := range entries {
+			buf.Write(entry)
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			buf.WriteByte(fingerprintNil)
+			return nil
+		}
+		// Maps may be reached through an unexported field, where Interface is not allowed
+		switch v.Type() {
+		case concurrentMapPtrType:
+			return writeFingerprintValue(buf, guard, path, (*ConcurrentMap)(v.UnsafePointer()))
+		case orderedMapPtrType:
+			return writeFingerprintValue(buf, guard, path, (*OrderedMap)(v.UnsafePointer()))
+		}
+		key, err := guard.enter(path, v)
+		if err != nil {
+			return err
+		}
+		defer guard.leave(key)
+
+		buf.WriteByte(fingerprintPresent)
+		return writeFingerprintReflect(buf, guard, path, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			buf.WriteByte(fingerprintNil)
+			return nil
+		}
+		elem := v.Elem()
+		buf.WriteByte(fingerprintPresent)
+		writeFingerprintString(buf, elem.Type().String())
+		return writeFingerprintReflect(buf, guard, path, elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if err := writeFingerprintReflect(buf, guard, appendPath(path, v.Type().Field(i).Name), v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Func:
+		// Non-nil functions are never deeply equal, so only nil-ness is encoded
+		if v.IsNil() {
+			buf.WriteByte(fingerprintNil)
+		} else {
+			buf.WriteByte(fingerprintPresent)
+		}
+	default:
+		// Channels and unsafe pointers are deeply equal only when identical
+		writeFingerprintUint(buf, uint64(v.Pointer()))
+	}
+	return nil
+}
+
+var (
+	concurrentMapPtrType = reflect.TypeOf((*ConcurrentMap)(nil))
+	orderedMapPtrType    = reflect.TypeOf((*OrderedMap)(nil))
+)
+
+// Returns the map key `k` as a path element of CycleError.
+func fingerprintPathElem(k reflect.Value) interface{} {
+	if k.CanInterface() {
+		return k.Interface()
+	}
+	return fmt.Sprint(k)
+}
+
+func writeFingerprintString(buf *bytes.Buffer, s string) {
+	writeFingerprintLength(buf, len(s))
+	buf.WriteString(s)
+}
+
+func writeFingerprintUint(buf *bytes.Buffer, n uint64) {
+	var x [8]byte
+	binary.BigEndian.PutUint64(x[:], n)
+	buf.Write(x[:])
+}
+
+// Writes the `f` so that 0 and -0, which are equal, get the same encoding.
+func writeFingerprintFloat(buf *bytes.Buffer, f float64) {
+	if f == 0 {
+		f = 0
+	}
+	writeFingerprintUint(buf, math.Float64bits(f))
+}
+
+func writeFingerprintLength(buf *bytes.Buffer, n int) {
+	var x [binary.MaxVarintLen64]byte
+	buf.Write(x[:binary.PutUvarint(x[:], uint64(n))])
+}
@@ -0,0 +1,185 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestFingerprint(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias        string
+		A                *ConcurrentMap
+		B                *ConcurrentMap
+		ExpectedSameHash bool
+	}{
+		{
+			TestAlias:        "Equal flat maps",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key2": 123, "key1": "stringValue"}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Different values",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 321}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Same printed value of different types",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": 123}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": int64(123)}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Key and value boundaries",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"ab": "c"}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"a": "bc"}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Equal nested maps",
+			A:                MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue", "key2": 123}}),
+			B:                MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key2": 123, "key1": "stringValue"}}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Different nested maps",
+			A:                MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "stringValue"}}),
+			B:                MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"Map": map[interface{}]interface{}{"key1": "otherValue"}}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Maps inside slices",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Pointers to equal values",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintIntPtr(1)}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintIntPtr(1)}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Pointers to different values",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintIntPtr(1)}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintIntPtr(2)}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Same elements in slices of different types",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": []int{1}}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": []interface{}{1}}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Equal structs holding maps",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintStruct{Map: map[string]int{"a": 1, "b": 2}, ptr: fingerprintIntPtr(3)}}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintStruct{Map: map[string]int{"b": 2, "a": 1}, ptr: fingerprintIntPtr(3)}}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Nil vs empty map inside of a struct",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintStruct{}}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintStruct{Map: map[string]int{}}}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "Zero vs negative zero",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": 0.0}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": math.Copysign(0, -1)}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Equal ordered maps",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintOrdered("a", "b")}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintOrdered("a", "b")}),
+			ExpectedSameHash: true,
+		},
+		{
+			TestAlias:        "Ordered maps in different order",
+			A:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintOrdered("a", "b")}),
+			B:                MakeConcurrentCopy(map[interface{}]interface{}{"key": fingerprintOrdered("b", "a")}),
+			ExpectedSameHash: false,
+		},
+		{
+			TestAlias:        "new(ConcurrentMap) vs New(0)",
+			A:                new(ConcurrentMap),
+			B:                New(0),
+			ExpectedSameHash: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		a := testCase.A
+		b := testCase.B
+		expectedSameHash := testCase.ExpectedSameHash
+
+		testFn := func(t *testing.T) {
+
+			aHash, aError := a.Fingerprint()
+			bHash, bError := b.Fingerprint()
+
+			if aError != nil || bError != nil {
+				t.Errorf("%s :: Fingerprint() returned unexpected errors %v and %v ", testAlias, aError, bError)
+			}
+			if (aHash == bHash) != expectedSameHash {
+				t.Errorf("%s :: a.Fingerprint() returned %s and b.Fingerprint() returned %s while expected them to be equal: %v ", testAlias, aHash, bHash, expectedSameHash)
+			}
+			if a.Equal(b) != expectedSameHash {
+				t.Errorf("%s :: a.Equal(b) disagrees with the expected fingerprint equality %v ", testAlias, expectedSameHash)
+			}
+			if aHashAgain, _ := a.Fingerprint(); aHash != aHashAgain {
+				t.Errorf("%s :: a.Fingerprint() is not stable between calls ", testAlias)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestFingerprintCycleInValue(t *testing.T) {
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+
+	cm := MakeConcurrentCopy(map[interface{}]interface{}{"key": n})
+	if _, err := cm.Fingerprint(); err == nil {
+		t.Errorf("Fingerprint() of a map holding a cyclic value returned no error ")
+	}
+}
+
+type fingerprintStruct struct {
+	Map map[string]int
+	ptr *int
+}
+
+func fingerprintIntPtr(i int) *int {
+	return &i
+}
+
+func fingerprintOrdered(keys ...string) *OrderedMap {
+	om := NewOrdered()
+	for _, key := range keys {
+		om.Set(key, MakeConcurrentCopy(map[interface{}]interface{}{key: len(key)}))
+	}
+	return om
+}
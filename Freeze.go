@@ -0,0 +1,83 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"errors"
+	"reflect"
+)
+
+// The map is frozen and cannot be modified, see Freeze.
+var ErrFrozen = errors.New("concurrentmap: map is frozen")
+
+// Makes the map and all nested maps permanently immutable.
+//
+// Nested maps are reached the same way recursive operations reach them: directly or through slices and arrays.
+// Subsequent TrySet, TrySetIfNotExists and TryRemove return ErrFrozen, while Set, SetIfNotExists, Remove,
+// batch methods and UnmarshalJSON panic with or return it. Slices are not copied, so their elements can still be assigned.
+// Clones of a frozen map are not frozen.
+//
+// It is safe to call concurrently with modifications and on self-referencing maps.
+func (this *ConcurrentMap) Freeze() {
+	freezeValue(map[visitKey]bool{}, this)
+}
+
+// Returns true if the map was frozen by Freeze.
+func (this *ConcurrentMap) IsFrozen() bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return this.frozen
+}
+
+// Freezes maps reachable from `v`. Maps already frozen are not walked again and `visited` tracks slices,
+// so cycles end the walk instead of failing it.
+func freezeValue(visited map[visitKey]bool, v interface{}) {
+	if cm, ok := v.(*ConcurrentMap); ok {
+		if cm == nil {
+			return
+		}
+		cm.lock.Lock()
+		if cm.frozen {
+			cm.lock.Unlock()
+			return
+		}
+		cm.frozen = true
+		values := make([]interface{}, 0, len(cm.items))
+		for _, value := range cm.items {
+			values = append(values, value)
+		}
+		cm.lock.Unlock()
+
+		for _, value := range values {
+			freezeValue(visited, value)
+		}
+		return
+	}
+
+	if isSequence(v) {
+		seq := reflect.ValueOf(v)
+		if seq.Kind() == reflect.Slice {
+			key := visitKey{typ: seq.Type(), ptr: seq.Pointer()}
+			if visited[key] {
+				return
+			}
+			visited[key] = true
+		}
+		for i := 0; i < seq.Len(); i++ {
+			freezeValue(visited, seq.Index(i).Interface())
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestFreeze(t *testing.T) {
+
+	nested := MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})
+	inSlice := MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})
+	root := MakeConcurrentCopy(map[interface{}]interface{}{
+		"Map":   nested,
+		"Slice": []interface{}{1, inSlice},
+	})
+	root.Set("Self", root)
+
+	root.Freeze()
+
+	testCases := []struct {
+		TestAlias string
+		Map       *ConcurrentMap
+	}{
+		{TestAlias: "Root map", Map: root},
+		{TestAlias: "Nested map", Map: nested},
+		{TestAlias: "Map inside slice", Map: inSlice},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Map
+
+		testFn := func(t *testing.T) {
+
+			if !cm.IsFrozen() {
+				t.Errorf("%s :: cm.IsFrozen() returned false while expected true ", testAlias)
+			}
+			if err := cm.TrySet("key", "otherValue"); !errors.Is(err, ErrFrozen) {
+				t.Errorf("%s :: cm.TrySet(...) returned error %v while expected %v ", testAlias, err, ErrFrozen)
+			}
+			if _, err := cm.TrySetIfNotExists("otherKey", "value"); !errors.Is(err, ErrFrozen) {
+				t.Errorf("%s :: cm.TrySetIfNotExists(...) returned error %v while expected %v ", testAlias, err, ErrFrozen)
+			}
+			if err := cm.TryRemove("key"); !errors.Is(err, ErrFrozen) {
+				t.Errorf("%s :: cm.TryRemove(...) returned error %v while expected %v ", testAlias, err, ErrFrozen)
+			}
+			if err := cm.UnmarshalJSON([]byte(`{"key":"otherValue"}`)); !errors.Is(err, ErrFrozen) {
+				t.Errorf("%s :: cm.UnmarshalJSON(...) returned error %v while expected %v ", testAlias, err, ErrFrozen)
+			}
+
+			for name, modifyFn := range map[string]func(){
+				"Set":        func() { cm.Set("key", "otherValue") },
+				"Remove":     func() { cm.Remove("key") },
+				"SetMany":    func() { cm.SetMany(map[interface{}]interface{}{"key": "otherValue"}) },
+				"RemoveMany": func() { cm.RemoveMany([]interface{}{"key"}) },
+			} {
+				actualPanic := func() (recovered interface{}) {
+					defer func() { recovered = recover() }()
+					modifyFn()
+					return nil
+				}()
+				if actualPanic != ErrFrozen {
+					t.Errorf("%s :: cm.%s(...) panicked with %v while expected %v ", testAlias, name, actualPanic, ErrFrozen)
+				}
+			}
+
+			if actualValue, _ := nested.Get("key"); actualValue != "value" {
+				t.Errorf("%s :: frozen map was modified, Get returned %v ", testAlias, actualValue)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+	clone := root.Clone()
+	if clone.IsFrozen() {
+		t.Errorf("root.Clone().IsFrozen() returned true while expected false ")
+	}
+	if err := clone.TrySet("key", "value"); err != nil {
+		t.Errorf("root.Clone().TrySet(...) returned unexpected error %v ", err)
+	}
+
+}
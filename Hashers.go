@@ -0,0 +1,67 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"bytes"
+	"hash/maphash"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Seed of hashes computed by the package, random per process.
+var hashSeed = maphash.MakeSeed()
+
+// Hashes `[]byte` key, to be used with EqualBytes by NewCustom. Panics if the key is not `[]byte`.
+func HashBytes(key interface{}) uint64 {
+	return maphash.Bytes(hashSeed, key.([]byte))
+}
+
+// Reports whether `[]byte` keys are equal, to be used with HashBytes by NewCustom.
+func EqualBytes(a, b interface{}) bool {
+	return bytes.Equal(a.([]byte), b.([]byte))
+}
+
+// Hashes string key ignoring case, to be used with EqualFoldString by NewCustom. Panics if the key is not a string.
+//
+// Every rune is hashed as the smallest rune of its Unicode case folding orbit,
+// so strings equal under strings.EqualFold always get equal hashes.
+func HashFoldString(key interface{}) uint64 {
+	s := key.(string)
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+	var buf [utf8.UTFMax]byte
+	for _, r := range s {
+		h.Write(buf[:utf8.EncodeRune(buf[:], foldRune(r))])
+	}
+	return h.Sum64()
+}
+
+// Reports whether string keys are equal ignoring case, to be used with HashFoldString by NewCustom.
+func EqualFoldString(a, b interface{}) bool {
+	return strings.EqualFold(a.(string), b.(string))
+}
+
+// Returns the smallest rune equivalent to `r` under Unicode simple case folding.
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
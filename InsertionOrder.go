@@ -0,0 +1,86 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+// Makes the map remember the order keys were inserted in, e.g. to re-serialize decoded documents the same way.
+//
+// Setting an existing key keeps its position, while removing it forgets the position. Keys and MarshalJSON follow the order,
+// and UnmarshalJSON inserts keys in the order of the document, decoding nested objects into maps with the same option.
+// Every write maintains the order at O(1) cost. Clone and DeepClone keep the order as well.
+func WithInsertionOrder() Option {
+	return func(cm *ConcurrentMap) {
+		cm.order = NewOrdered()
+		for key := range cm.items {
+			cm.order.Set(key, nil)
+		}
+	}
+}
+
+// Returns keys of the map in insertion order if the map was created WithInsertionOrder, in unspecified order otherwise.
+func (this *ConcurrentMap) Keys() []interface{} {
+	this.readLock()
+	defer this.lock.RUnlock()
+
+	if this.order != nil {
+		return this.order.Keys()
+	}
+	keys := make([]interface{}, 0, len(this.items))
+	for key := range this.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Returns a consistent snapshot of entries in insertion order if the map was created WithInsertionOrder,
+// ordered by keys printed with fmt.Sprint otherwise.
+func snapshotEntries(cm *ConcurrentMap) []Entry {
+	if cm == nil {
+		return []Entry{}
+	}
+	cm.readLock()
+	defer cm.lock.RUnlock()
+
+	entries := make([]Entry, 0, len(cm.items))
+	if cm.order != nil {
+		for _, key := range cm.order.Keys() {
+			entries = append(entries, Entry{Key: key, Value: cm.items[key]})
+		}
+		return entries
+	}
+	for key, value := range cm.items {
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// Returns a consistent snapshot of items together with keys in insertion order if the map was created WithInsertionOrder,
+// or nil keys otherwise.
+func snapshotOrderedItems(cm *ConcurrentMap) (map[interface{}]interface{}, []interface{}) {
+	if cm == nil {
+		return map[interface{}]interface{}{}, nil
+	}
+	cm.readLock()
+	defer cm.lock.RUnlock()
+
+	items := make(map[interface{}]interface{}, len(cm.items))
+	for key, value := range cm.items {
+		items[key] = value
+	}
+	if cm.order == nil {
+		return items, nil
+	}
+	return items, cm.order.Keys()
+}
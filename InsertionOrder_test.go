@@ -0,0 +1,131 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestInsertionOrder(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias    string
+		ModifyFn     func(cm *ConcurrentMap)
+		ExpectedKeys []interface{}
+	}{
+		{
+			TestAlias: "Keys in insertion order",
+			ModifyFn: func(cm *ConcurrentMap) {
+				cm.Set("zeta", 1)
+				cm.SetIfNotExists("alpha", 2)
+				cm.SetMany(map[interface{}]interface{}{"mid": 3})
+			},
+			ExpectedKeys: []interface{}{"zeta", "alpha", "mid"},
+		},
+		{
+			TestAlias: "Setting an existing key keeps its position",
+			ModifyFn: func(cm *ConcurrentMap) {
+				cm.Set("zeta", 1)
+				cm.Set("alpha", 2)
+				cm.Set("zeta", 3)
+			},
+			ExpectedKeys: []interface{}{"zeta", "alpha"},
+		},
+		{
+			TestAlias: "Removal forgets the position",
+			ModifyFn: func(cm *ConcurrentMap) {
+				cm.Set("zeta", 1)
+				cm.Set("alpha", 2)
+				cm.Remove("zeta")
+				cm.RemoveMany([]interface{}{"missing"})
+				cm.Set("zeta", 3)
+			},
+			ExpectedKeys: []interface{}{"alpha", "zeta"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		modifyFn := testCase.ModifyFn
+		expectedKeys := testCase.ExpectedKeys
+
+		testFn := func(t *testing.T) {
+
+			cm := NewWithOptions(0, WithInsertionOrder())
+			modifyFn(cm)
+
+			if actualKeys := cm.Keys(); !reflect.DeepEqual(actualKeys, expectedKeys) {
+				t.Errorf("%s :: cm.Keys() returned %#v while expected %#v ", testAlias, actualKeys, expectedKeys)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestInsertionOrderJSON(t *testing.T) {
+
+	data := `{"zeta":1,"alpha":{"y":[{"b":1,"a":2}],"x":null},"mid":"a"}`
+
+	cm := NewWithOptions(0, WithInsertionOrder())
+	if err := json.Unmarshal([]byte(data), cm); err != nil {
+		t.Fatalf("json.Unmarshal(...) returned unexpected error %v ", err)
+	}
+	if nested, _ := cm.Get("alpha"); reflect.TypeOf(nested) != reflect.TypeOf(cm) {
+		t.Errorf("cm.Get(\"alpha\") returned %T while expected *ConcurrentMap ", nested)
+	}
+
+	actualJSON, err := json.Marshal(cm)
+	if err != nil {
+		t.Fatalf("json.Marshal(cm) returned unexpected error %v ", err)
+	}
+	if string(actualJSON) != data {
+		t.Errorf("json.Marshal(cm) returned \r\n %s \r\n while expected the document order \r\n %s ", actualJSON, data)
+	}
+	if err := json.Unmarshal([]byte(`null`), cm); err != nil || cm.Len() != 3 {
+		t.Errorf("json.Unmarshal(null) returned %v and left %d elements while expected a no-op ", err, cm.Len())
+	}
+
+}
+
+func TestInsertionOrderClone(t *testing.T) {
+
+	cm := NewWithOptions(0, WithInsertionOrder())
+	for _, key := range []string{"zeta", "alpha", "mid"} {
+		cm.Set(key, MakeConcurrentCopy(map[interface{}]interface{}{}))
+	}
+	expectedKeys := []interface{}{"zeta", "alpha", "mid"}
+
+	clone := cm.Clone()
+	clone.Set("beta", 1)
+	if actualKeys := clone.Keys(); !reflect.DeepEqual(actualKeys, append(expectedKeys, "beta")) {
+		t.Errorf("clone.Keys() returned %#v while expected the order of the original followed by beta ", actualKeys)
+	}
+	if actualKeys := cm.Keys(); !reflect.DeepEqual(actualKeys, expectedKeys) {
+		t.Errorf("cm.Keys() returned %#v while expected the original untouched by the clone ", actualKeys)
+	}
+
+	deepClone, err := cm.DeepClone()
+	if err != nil {
+		t.Fatalf("cm.DeepClone() returned unexpected error %v ", err)
+	}
+	if actualKeys := deepClone.Keys(); !reflect.DeepEqual(actualKeys, expectedKeys) {
+		t.Errorf("deepClone.Keys() returned %#v while expected %#v ", actualKeys, expectedKeys)
+	}
+}
@@ -0,0 +1,135 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "fmt"
+
+// Represents the kind of an intercepted Operation.
+type OperationKind int
+
+// Kinds of operations
+const (
+	// Get, TryGet and keys of GetMany.
+	OperationGet OperationKind = iota
+	// Set, TrySet and entries of SetMany.
+	OperationSet
+	// SetIfNotExists, TrySetIfNotExists and entries of SetManyIfNotExists.
+	OperationSetIfNotExists
+	// Remove, TryRemove and keys of RemoveMany.
+	OperationRemove
+)
+
+// Returns short human-readable name of the operation kind.
+func (this OperationKind) String() string {
+	switch this {
+	case OperationGet:
+		return "get"
+	case OperationSet:
+		return "set"
+	case OperationSetIfNotExists:
+		return "setIfNotExists"
+	case OperationRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("OperationKind(%d)", int(this))
+}
+
+// Represents a single operation passed through interceptors of a ConcurrentMap.
+//
+// Interceptors may change Key and Value before invoking the operation, e.g. to normalize keys,
+// and may inspect or change Value and Found after it, e.g. to decode values.
+type Operation struct {
+	Kind OperationKind
+	Key  interface{}
+	// The value to set, or the value found by OperationGet once invoked
+	Value interface{}
+	// Once invoked, whether the key was present for OperationGet or the value was set for OperationSetIfNotExists
+	Found bool
+}
+
+// Represents the rest of the interceptor chain, ending with the operation on the map itself.
+type Invoker func(op *Operation) error
+
+// Represents a middleware of ConcurrentMap operations, see WithInterceptors.
+//
+// It observes, modifies or rejects the `op`: to let the operation proceed it calls `invoke` and
+// usually returns its error; to reject it, it returns an error without calling `invoke`.
+type Interceptor func(op *Operation, invoke Invoker) error
+
+// Installs interceptors, called in the given order around Get, Set, SetIfNotExists, Remove and their checked counterparts,
+// the first one being outermost. Options applied more than once append interceptors to previously installed ones.
+//
+// Errors returned by interceptors, as well as ErrUnhashableKey and ErrKeyType for keys they passed on, are returned by Try* methods
+// while the other methods panic with them. Batch methods pass every key through the interceptors separately,
+// so with interceptors installed they neither lock the map once nor check all keys before changing it.
+// Items and methods built on it, such as Clone or Diff, are not intercepted.
+//
+// Maps without interceptors only pay for a nil check per operation. Clones of the map keep its interceptors.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(cm *ConcurrentMap) {
+		all := make([]Interceptor, 0, len(cm.interceptors)+len(interceptors))
+		cm.setInterceptors(append(append(all, cm.interceptors...), interceptors...))
+	}
+}
+
+// Installs `interceptors`, composing them into the chain around the operations of the map.
+func (this *ConcurrentMap) setInterceptors(interceptors []Interceptor) {
+	this.interceptors = interceptors
+	this.invoke = this.apply
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], this.invoke
+		this.invoke = func(op *Operation) error {
+			return interceptor(op, next)
+		}
+	}
+}
+
+// Passes the `op` through interceptors and panics with the error, if any.
+func (this *ConcurrentMap) mustIntercept(op *Operation) {
+	if err := this.invoke(op); err != nil {
+		panic(err)
+	}
+}
+
+// Performs the `op` on the map itself, as the innermost Invoker.
+func (this *ConcurrentMap) apply(op *Operation) error {
+	switch op.Kind {
+	case OperationGet:
+		if err := checkHashable(op.Key); err != nil {
+			return err
+		}
+		op.Value, op.Found = this.get(op.Key)
+	case OperationSet:
+		if err := this.checkKey(op.Key); err != nil {
+			return err
+		}
+		return this.set(op.Key, op.Value)
+	case OperationSetIfNotExists:
+		if err := this.checkKey(op.Key); err != nil {
+			return err
+		}
+		var err error
+		op.Found, err = this.setIfNotExists(op.Key, op.Value)
+		return err
+	case OperationRemove:
+		if err := checkHashable(op.Key); err != nil {
+			return err
+		}
+		return this.remove(op.Key)
+	default:
+		return fmt.Errorf("concurrentmap: unknown operation kind %v", op.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestWithInterceptors(t *testing.T) {
+
+	errDenied := errors.New("denied")
+
+	lowerKeys := func(op *Operation, invoke Invoker) error {
+		if key, ok := op.Key.(string); ok {
+			op.Key = strings.ToLower(key)
+		}
+		return invoke(op)
+	}
+	denyRemove := func(op *Operation, invoke Invoker) error {
+		if op.Kind == OperationRemove {
+			return errDenied
+		}
+		return invoke(op)
+	}
+	upperValues := func(op *Operation, invoke Invoker) error {
+		err := invoke(op)
+		if s, ok := op.Value.(string); ok && op.Kind == OperationGet {
+			op.Value = strings.ToUpper(s)
+		}
+		return err
+	}
+
+	testCases := []struct {
+		TestAlias     string
+		Interceptors  []Interceptor
+		OperationFn   func(cm *ConcurrentMap) error
+		ExpectedItems map[interface{}]interface{}
+		ExpectedError error
+	}{
+		{
+			TestAlias:     "Normalized key on set",
+			Interceptors:  []Interceptor{lowerKeys},
+			OperationFn:   func(cm *ConcurrentMap) error { return cm.TrySet("KEY2", 123) },
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:    "Normalized key on get",
+			Interceptors: []Interceptor{lowerKeys},
+			OperationFn: func(cm *ConcurrentMap) error {
+				val, ok, err := cm.TryGet("KEY1")
+				if val != "stringValue" || !ok {
+					return errors.New("unexpected result")
+				}
+				return err
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:     "Rejected remove",
+			Interceptors:  []Interceptor{denyRemove},
+			OperationFn:   func(cm *ConcurrentMap) error { return cm.TryRemove("key1") },
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedError: errDenied,
+		},
+		{
+			TestAlias:    "Modified result of get",
+			Interceptors: []Interceptor{upperValues},
+			OperationFn: func(cm *ConcurrentMap) error {
+				if val, ok := cm.Get("key1"); val != "STRINGVALUE" || !ok {
+					return errors.New("unexpected result")
+				}
+				return nil
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:    "Result of set if not exists",
+			Interceptors: []Interceptor{lowerKeys},
+			OperationFn: func(cm *ConcurrentMap) error {
+				if ok, err := cm.TrySetIfNotExists("KEY1", "otherValue"); ok || err != nil {
+					return errors.New("unexpected result")
+				}
+				return nil
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:     "Unhashable key passed on by interceptor",
+			Interceptors:  []Interceptor{lowerKeys},
+			OperationFn:   func(cm *ConcurrentMap) error { return cm.TrySet([]int{1}, "value") },
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedError: ErrUnhashableKey,
+		},
+		{
+			TestAlias:    "Batch operations",
+			Interceptors: []Interceptor{lowerKeys, denyRemove},
+			OperationFn: func(cm *ConcurrentMap) error {
+				cm.SetMany(map[interface{}]interface{}{"KEY2": 123})
+				if inserted := cm.SetManyIfNotExists(map[interface{}]interface{}{"KEY1": "otherValue", "KEY3": 4.56}); !reflect.DeepEqual(inserted, []interface{}{"KEY3"}) {
+					return errors.New("unexpected inserted keys")
+				}
+				if found, missing := cm.GetMany([]interface{}{"KEY1", "KEY4"}); !reflect.DeepEqual(found, map[interface{}]interface{}{"KEY1": "stringValue"}) || !reflect.DeepEqual(missing, []interface{}{"KEY4"}) {
+					return errors.New("unexpected found keys")
+				}
+				return nil
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56},
+			ExpectedError: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		interceptors := testCase.Interceptors
+		operationFn := testCase.OperationFn
+		expectedItems := testCase.ExpectedItems
+		expectedError := testCase.ExpectedError
+
+		testFn := func(t *testing.T) {
+
+			cm := NewWithOptions(0, WithInterceptors(interceptors...))
+			cm.SetMany(map[interface{}]interface{}{"key1": "stringValue"})
+
+			actualError := operationFn(cm)
+
+			if !errors.Is(actualError, expectedError) || (actualError == nil) != (expectedError == nil) {
+				t.Errorf("%s :: operation returned error %v while expected %v ", testAlias, actualError, expectedError)
+			}
+			if actualItems := cm.Items(); !reflect.DeepEqual(actualItems, expectedItems) {
+				t.Errorf("%s :: cm.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestInterceptorsOrder(t *testing.T) {
+
+	trace := []string{}
+	tracing := func(name string) Interceptor {
+		return func(op *Operation, invoke Invoker) error {
+			trace = append(trace, name+" before "+op.Kind.String())
+			err := invoke(op)
+			trace = append(trace, name+" after "+op.Kind.String())
+			return err
+		}
+	}
+
+	cm := NewWithOptions(0, WithInterceptors(tracing("first")), WithInterceptors(tracing("second")))
+	cm.Clone().Set("key1", "stringValue")
+
+	expectedTrace := []string{"first before set", "second before set", "second after set", "first after set"}
+	if !reflect.DeepEqual(trace, expectedTrace) {
+		t.Errorf("interceptors traced \r\n %#v \r\n while expected \r\n %#v ", trace, expectedTrace)
+	}
+
+}
+
+func TestInterceptorRejectionPanics(t *testing.T) {
+
+	errDenied := errors.New("denied")
+	cm := NewWithOptions(0, WithInterceptors(func(op *Operation, invoke Invoker) error { return errDenied }))
+
+	actualPanic := func() (recovered interface{}) {
+		defer func() { recovered = recover() }()
+		cm.Set("key1", "stringValue")
+		return nil
+	}()
+
+	if actualPanic != errDenied {
+		t.Errorf("cm.Set(...) panicked with %v while expected %v ", actualPanic, errDenied)
+	}
+
+}
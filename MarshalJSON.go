@@ -0,0 +1,156 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler).
+//
+// It is the inverse of UnmarshalJSON: nested *ConcurrentMap and *OrderedMap values are rendered as JSON objects, also inside
+// `[]interface{}` and `[]*ConcurrentMap`. Keys are rendered in insertion order if the map was created WithInsertionOrder,
+// sorted otherwise; non-string keys are rendered with fmt.Sprint. Returns *CycleError if the map references itself.
+//
+// It is safe to call concurrently with modifications; each nested map is rendered from its own consistent snapshot.
+func (this *ConcurrentMap) MarshalJSON() ([]byte, error) {
+	value, err := toJSONValue(cycleGuard{}, nil, this)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Converts nested maps of the `v` into jsonObject values, so that json.Marshal renders them in order without calling
+// MarshalJSON of the maps, each of which would start with an empty guard and never detect cycles.
+func toJSONValue(guard cycleGuard, path []interface{}, v interface{}) (interface{}, error) {
+	var entries []Entry
+	switch x := v.(type) {
+	case *ConcurrentMap:
+		key, err := guard.enterMap(path, x)
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		entries = snapshotEntries(x)
+	case *OrderedMap:
+		if x == nil {
+			return nil, nil
+		}
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		entries = x.Entries()
+	case jsonEntrier:
+		if reflect.ValueOf(x).IsNil() {
+			return nil, nil
+		}
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		entries = x.jsonEntries()
+	case []interface{}:
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		sl := make([]interface{}, len(x))
+		for i, j := range x {
+			if sl[i], err = toJSONValue(guard, appendPath(path, i), j); err != nil {
+				return nil, err
+			}
+		}
+		return sl, nil
+	case []*ConcurrentMap:
+		sl := make([]interface{}, len(x))
+		for i, j := range x {
+			var err error
+			if sl[i], err = toJSONValue(guard, appendPath(path, i), j); err != nil {
+				return nil, err
+			}
+		}
+		return sl, nil
+	default:
+		return v, nil
+	}
+
+	object := make(jsonObject, len(entries))
+	for i, entry := range entries {
+		value, err := toJSONValue(guard, appendPath(path, entry.Key), entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		object[i] = Entry{Key: stringKey(entry.Key), Value: value}
+	}
+	return object, nil
+}
+
+// Implemented by other map types of the package, so that toJSONValue renders them as JSON objects under the guard.
+type jsonEntrier interface {
+	// Returns a consistent snapshot of entries in the order to render them
+	jsonEntries() []Entry
+}
+
+// Renders the map `m` as a JSON object, see ConcurrentMap.MarshalJSON.
+func marshalJSONEntries(m jsonEntrier) ([]byte, error) {
+	value, err := toJSONValue(cycleGuard{}, nil, m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Orders `entries` by keys printed with fmt.Sprint, the way json.Marshal orders keys of Go maps.
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool { return stringKey(entries[i].Key) < stringKey(entries[j].Key) })
+}
+
+// The jsonObject type represents members of a JSON object in order, holding string keys.
+type jsonObject []Entry
+
+func (this jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range this {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
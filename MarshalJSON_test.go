@@ -0,0 +1,88 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestMarshalJSON(t *testing.T) {
+
+	ordered := NewOrdered()
+	ordered.Set("b", 1)
+	ordered.Set("a", 2)
+
+	testCases := []struct {
+		TestAlias    string
+		Map          *ConcurrentMap
+		ExpectedJSON string
+	}{
+		{
+			TestAlias:    "Empty map",
+			Map:          New(0),
+			ExpectedJSON: `{}`,
+		},
+		{
+			TestAlias:    "Keys are sorted",
+			Map:          MakeConcurrentCopy(map[interface{}]interface{}{"zeta": 1, "alpha": "a", 3: true}),
+			ExpectedJSON: `{"3":true,"alpha":"a","zeta":1}`,
+		},
+		{
+			TestAlias: "Nested maps",
+			Map: MakeConcurrentCopy(map[interface{}]interface{}{
+				"Map":     MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}),
+				"Ordered": ordered,
+				"Slice":   []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": 1}), 2},
+			}),
+			ExpectedJSON: `{"Map":{"key":"value"},"Ordered":{"b":1,"a":2},"Slice":[{"key":1},2]}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Map
+		expectedJSON := testCase.ExpectedJSON
+
+		testFn := func(t *testing.T) {
+
+			actualJSON, err := json.Marshal(cm)
+			if err != nil {
+				t.Fatalf("%s :: json.Marshal(cm) returned unexpected error %v ", testAlias, err)
+			}
+			if string(actualJSON) != expectedJSON {
+				t.Errorf("%s :: json.Marshal(cm) returned \r\n %s \r\n while expected \r\n %s ", testAlias, actualJSON, expectedJSON)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestMarshalJSONCycle(t *testing.T) {
+
+	cm := New(0)
+	cm.Set("Self", []interface{}{cm})
+
+	_, err := cm.MarshalJSON()
+	var cycleError *CycleError
+	if !errors.As(err, &cycleError) {
+		t.Errorf("cm.MarshalJSON() returned %v while expected *CycleError ", err)
+	}
+
+}
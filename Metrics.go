@@ -0,0 +1,167 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Renders statistics of the map as JSON object, so the map implements [expvar.Var](https://golang.org/pkg/expvar/#Var)
+// and can be published with expvar.Publish.
+//
+// Maps created without WithStats render only their size, e.g. `{"size":3}`.
+func (this *ConcurrentMap) String() string {
+	var v interface{} = struct {
+		Size int `json:"size"`
+	}{this.Len()}
+	if stats, ok := this.Stats(); ok {
+		v = stats
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Stats consist of numbers only
+		panic(err)
+	}
+	return string(data)
+}
+
+// The MetricsHandler type represents http.Handler rendering statistics of registered maps
+// in [Prometheus text exposition format](https://prometheus.io/docs/instrumenting/exposition_formats/).
+//
+// Every map is rendered with the `map` label holding the name it was registered under.
+// Maps created without WithStats expose only their size. It is safe for concurrent use.
+type MetricsHandler struct {
+	maps map[string]*ConcurrentMap
+	lock sync.RWMutex
+}
+
+// Instantiates MetricsHandler with no registered maps.
+func NewMetricsHandler() *MetricsHandler {
+	return &MetricsHandler{maps: map[string]*ConcurrentMap{}}
+}
+
+// Registers the map under given name, replacing a map previously registered under the same name.
+func (this *MetricsHandler) Register(name string, cm *ConcurrentMap) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.maps[name] = cm
+}
+
+// Removes the map registered under given name, if any.
+func (this *MetricsHandler) Unregister(name string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	delete(this.maps, name)
+}
+
+// Represents statistics of a single registered map.
+type namedStats struct {
+	label string
+	stats Stats
+	ok    bool
+}
+
+// Represents a single metric family: its name, type, help and how to get its value out of statistics.
+type metricFamily struct {
+	name, typ, help string
+	value           func(stats Stats) uint64
+}
+
+var counterFamilies = []metricFamily{
+	{"concurrentmap_gets_total", "counter", "Number of looked up keys.", func(s Stats) uint64 { return s.Gets }},
+	{"concurrentmap_hits_total", "counter", "Number of looked up keys which were present.", func(s Stats) uint64 { return s.Hits }},
+	{"concurrentmap_misses_total", "counter", "Number of looked up keys which were absent.", func(s Stats) uint64 { return s.Misses }},
+	{"concurrentmap_sets_total", "counter", "Number of stored values.", func(s Stats) uint64 { return s.Sets }},
+	{"concurrentmap_removes_total", "counter", "Number of keys requested to be removed.", func(s Stats) uint64 { return s.Removes }},
+}
+
+// Renders statistics of all registered maps, ordered by name.
+func (this *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf := bufio.NewWriter(w)
+	this.writeMetrics(buf)
+	buf.Flush()
+}
+
+func (this *MetricsHandler) writeMetrics(w *bufio.Writer) {
+	this.lock.RLock()
+	names := make([]string, 0, len(this.maps))
+	for name := range this.maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	all := make([]namedStats, len(names))
+	for i, name := range names {
+		cm := this.maps[name]
+		all[i].label = `map="` + escapeLabelValue(name) + `"`
+		if all[i].stats, all[i].ok = cm.Stats(); !all[i].ok {
+			all[i].stats.Size = cm.Len()
+		}
+	}
+	this.lock.RUnlock()
+
+	writeHeader(w, "concurrentmap_size", "gauge", "Number of elements in the map.")
+	for _, ns := range all {
+		fmt.Fprintf(w, "concurrentmap_size{%s} %d\n", ns.label, ns.stats.Size)
+	}
+
+	for _, family := range counterFamilies {
+		writeHeader(w, family.name, family.typ, family.help)
+		for _, ns := range all {
+			if ns.ok {
+				fmt.Fprintf(w, "%s{%s} %d\n", family.name, ns.label, family.value(ns.stats))
+			}
+		}
+	}
+
+	writeHeader(w, "concurrentmap_lock_wait_seconds", "histogram", "Time spent waiting for the lock of the map.")
+	for _, ns := range all {
+		if !ns.ok {
+			continue
+		}
+		lockWait := ns.stats.LockWait
+		cumulative := uint64(0)
+		for i, count := range lockWait.Counts {
+			cumulative += count
+			le := "+Inf"
+			if bound := lockWait.Bound(i); bound >= 0 {
+				le = strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)
+			}
+			fmt.Fprintf(w, "concurrentmap_lock_wait_seconds_bucket{%s,le=\"%s\"} %d\n", ns.label, le, cumulative)
+		}
+		fmt.Fprintf(w, "concurrentmap_lock_wait_seconds_sum{%s} %s\n", ns.label, strconv.FormatFloat(lockWait.Sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "concurrentmap_lock_wait_seconds_count{%s} %d\n", ns.label, lockWait.Count)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Escapes backslashes, double quotes and line feeds, as required for label values.
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
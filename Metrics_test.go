@@ -0,0 +1,132 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+var _ expvar.Var = (*ConcurrentMap)(nil)
+
+func TestString(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias    string
+		Options      []Option
+		ExpectedKeys []string
+	}{
+		{
+			TestAlias:    "Map without stats",
+			Options:      nil,
+			ExpectedKeys: []string{"size"},
+		},
+		{
+			TestAlias:    "Map with stats",
+			Options:      []Option{WithStats()},
+			ExpectedKeys: []string{"gets", "hits", "lockWait", "misses", "removes", "sets", "size"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		options := testCase.Options
+		expectedKeys := testCase.ExpectedKeys
+
+		testFn := func(t *testing.T) {
+
+			cm := NewWithOptions(0, options...)
+			cm.Set("key1", "stringValue")
+
+			var actual map[string]interface{}
+			if err := json.Unmarshal([]byte(cm.String()), &actual); err != nil {
+				t.Fatalf("%s :: cm.String() returned invalid JSON %q: %v ", testAlias, cm.String(), err)
+			}
+			actualKeys := []string{}
+			for _, key := range []string{"gets", "hits", "lockWait", "misses", "removes", "sets", "size"} {
+				if _, ok := actual[key]; ok {
+					actualKeys = append(actualKeys, key)
+				}
+			}
+			if !reflect.DeepEqual(actualKeys, expectedKeys) {
+				t.Errorf("%s :: cm.String() returned keys %v while expected %v ", testAlias, actualKeys, expectedKeys)
+			}
+			if actual["size"] != 1.0 {
+				t.Errorf("%s :: cm.String() returned size %v while expected 1 ", testAlias, actual["size"])
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestMetricsHandler(t *testing.T) {
+
+	withStats := NewWithOptions(0, WithStats())
+	withStats.Set("key1", "stringValue")
+	withStats.Get("key1")
+	withStats.Get("missingKey")
+	withoutStats := New(0)
+	withoutStats.Set("key1", "stringValue")
+	withoutStats.Set("key2", 123)
+
+	handler := NewMetricsHandler()
+	handler.Register("routes", withStats)
+	handler.Register(`odd "name"\`, withoutStats)
+	handler.Register("removed", New(0))
+	handler.Unregister("removed")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	actualBody := recorder.Body.String()
+
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("handler returned Content-Type %q ", contentType)
+	}
+
+	expectedLines := []string{
+		"# TYPE concurrentmap_size gauge",
+		`concurrentmap_size{map="odd \"name\"\\"} 2`,
+		`concurrentmap_size{map="routes"} 1`,
+		"# TYPE concurrentmap_gets_total counter",
+		`concurrentmap_gets_total{map="routes"} 2`,
+		`concurrentmap_hits_total{map="routes"} 1`,
+		`concurrentmap_misses_total{map="routes"} 1`,
+		`concurrentmap_sets_total{map="routes"} 1`,
+		`concurrentmap_removes_total{map="routes"} 0`,
+		"# TYPE concurrentmap_lock_wait_seconds histogram",
+		`concurrentmap_lock_wait_seconds_bucket{map="routes",le="1e-06"} `,
+		`concurrentmap_lock_wait_seconds_bucket{map="routes",le="+Inf"} 4`,
+		`concurrentmap_lock_wait_seconds_count{map="routes"} 4`,
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(actualBody, expectedLine) {
+			t.Errorf("handler response does not contain %q: \r\n%s ", expectedLine, actualBody)
+		}
+	}
+
+	for _, unexpected := range []string{`concurrentmap_gets_total{map="odd`, `map="removed"`} {
+		if strings.Contains(actualBody, unexpected) {
+			t.Errorf("handler response contains %q: \r\n%s ", unexpected, actualBody)
+		}
+	}
+
+}
@@ -0,0 +1,185 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "strings"
+
+// The NamespaceMap type represents a view of string keys of a ConcurrentMap sharing a common prefix.
+//
+// Keys passed to and returned by the view are relative to the prefix, e.g. the key `invoice:42` of
+// the `billing:` namespace is stored as `billing:invoice:42` in the underlying map.
+// Modifications go through the underlying map, so its options, interceptors and Freeze apply to them.
+type NamespaceMap struct {
+	cm     *ConcurrentMap
+	prefix string
+}
+
+// Returns a view of string keys of the map starting with `prefix`.
+//
+// Keys under the prefix are enumerated without scanning the map, using a single sorted index of all string keys
+// shared by every namespace of the map. The first call builds the index and from then on every write of a string key
+// maintains it at O(log n) cost, regardless of the number of namespaces. The index is built without blocking writers:
+// existing keys are collected under the read lock and inserted with no lock held, while writes made meanwhile are
+// recorded and replayed once it is ready.
+func (this *ConcurrentMap) Namespace(prefix string) *NamespaceMap {
+	this.prefixOnce.Do(this.buildPrefixIndex)
+	return &NamespaceMap{cm: this, prefix: prefix}
+}
+
+// The prefixIndex type holds string keys of a ConcurrentMap in order, see Namespace.
+type prefixIndex struct {
+	// Keys of the map with nil values, or nil while being built
+	keys *SortedMap
+	// Changes made while being built, in order
+	journal []prefixChange
+}
+
+type prefixChange struct {
+	key     string
+	removed bool
+}
+
+func (this *ConcurrentMap) buildPrefixIndex() {
+	this.writeLock()
+	this.prefixes = &prefixIndex{}
+	this.lock.Unlock()
+
+	this.readLock()
+	existing := make([]string, 0, len(this.items))
+	for key := range this.items {
+		if s, ok := key.(string); ok {
+			existing = append(existing, s)
+		}
+	}
+	this.lock.RUnlock()
+
+	keys := NewSorted(compareStrings)
+	for _, key := range existing {
+		keys.Set(key, nil)
+	}
+
+	this.writeLock()
+	defer this.lock.Unlock()
+
+	// Changes made before the keys were collected are replayed as well, which is harmless since every change
+	// sets the final presence of its key
+	for _, change := range this.prefixes.journal {
+		if change.removed {
+			keys.Remove(change.key)
+		} else {
+			keys.Set(change.key, nil)
+		}
+	}
+	this.prefixes.keys = keys
+	this.prefixes.journal = nil
+}
+
+func compareStrings(a, b interface{}) int {
+	return strings.Compare(a.(string), b.(string))
+}
+
+// Adds the `key` to the prefix index. Must be called under the write lock.
+func (this *ConcurrentMap) indexKey(key interface{}) {
+	if s, ok := key.(string); ok {
+		if this.prefixes.keys == nil {
+			this.prefixes.journal = append(this.prefixes.journal, prefixChange{key: s})
+		} else {
+			this.prefixes.keys.Set(s, nil)
+		}
+	}
+}
+
+// Removes the `key` from the prefix index. Must be called under the write lock.
+func (this *ConcurrentMap) unindexKey(key interface{}) {
+	if s, ok := key.(string); ok {
+		if this.prefixes.keys == nil {
+			this.prefixes.journal = append(this.prefixes.journal, prefixChange{key: s, removed: true})
+		} else {
+			this.prefixes.keys.Remove(s)
+		}
+	}
+}
+
+// Calls `fn` for keys of the map starting with `prefix` in ascending order. Must be called under the lock.
+func (this *ConcurrentMap) rangePrefix(prefix string, fn func(key string)) {
+	var to interface{}
+	if end, ok := prefixEnd(prefix); ok {
+		to = end
+	}
+	this.prefixes.keys.Ascend(prefix, to, func(key, _ interface{}) bool {
+		fn(key.(string))
+		return true
+	})
+}
+
+// Returns the least string greater than every string starting with `prefix`, or false if there is none.
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// Returns the prefix of the namespace.
+func (this *NamespaceMap) Prefix() string {
+	return this.prefix
+}
+
+// Retrieves an element under given key relative to the prefix.
+// Returns false in case there is no entry associated with the key.
+func (this *NamespaceMap) Get(key string) (interface{}, bool) {
+	return this.cm.Get(this.prefix + key)
+}
+
+// Sets the given value under the specified key relative to the prefix, see ConcurrentMap.Set.
+func (this *NamespaceMap) Set(key string, val interface{}) {
+	this.cm.Set(this.prefix+key, val)
+}
+
+// Removes an element under given key relative to the prefix, see ConcurrentMap.Remove.
+func (this *NamespaceMap) Remove(key string) {
+	this.cm.Remove(this.prefix + key)
+}
+
+// Returns copy of content of the namespace with keys relative to the prefix.
+func (this *NamespaceMap) Items() map[string]interface{} {
+	this.cm.readLock()
+	defer this.cm.lock.RUnlock()
+
+	x := map[string]interface{}{}
+	this.cm.rangePrefix(this.prefix, func(key string) {
+		x[key[len(this.prefix):]] = this.cm.items[key]
+	})
+	return x
+}
+
+// Returns number of elements in the namespace. It costs O(log n) plus the number of elements.
+func (this *NamespaceMap) Len() int {
+	this.cm.readLock()
+	defer this.cm.lock.RUnlock()
+
+	n := 0
+	this.cm.rangePrefix(this.prefix, func(string) { n++ })
+	return n
+}
+
+// Returns a view of keys of the namespace starting with `prefix`, i.e. a namespace nested into this one.
+func (this *NamespaceMap) Namespace(prefix string) *NamespaceMap {
+	return this.cm.Namespace(this.prefix + prefix)
+}
@@ -0,0 +1,166 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestNamespace(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Items         map[interface{}]interface{}
+		Prefix        string
+		ModifyFn      func(cm *ConcurrentMap, ns *NamespaceMap)
+		ExpectedItems map[string]interface{}
+		ExpectedMap   map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "Existing keys are indexed",
+			Items:         map[interface{}]interface{}{"billing:invoice:42": 1, "billing:invoice:43": 2, "shipping:order:1": 3, 123: 4},
+			Prefix:        "billing:",
+			ModifyFn:      func(cm *ConcurrentMap, ns *NamespaceMap) {},
+			ExpectedItems: map[string]interface{}{"invoice:42": 1, "invoice:43": 2},
+			ExpectedMap:   map[interface{}]interface{}{"billing:invoice:42": 1, "billing:invoice:43": 2, "shipping:order:1": 3, 123: 4},
+		},
+		{
+			TestAlias: "Modifications through the view",
+			Items:     map[interface{}]interface{}{"billing:invoice:42": 1},
+			Prefix:    "billing:",
+			ModifyFn: func(cm *ConcurrentMap, ns *NamespaceMap) {
+				ns.Set("invoice:43", 2)
+				ns.Remove("invoice:42")
+			},
+			ExpectedItems: map[string]interface{}{"invoice:43": 2},
+			ExpectedMap:   map[interface{}]interface{}{"billing:invoice:43": 2},
+		},
+		{
+			TestAlias: "Modifications of the underlying map",
+			Items:     map[interface{}]interface{}{"billing:invoice:42": 1},
+			Prefix:    "billing:",
+			ModifyFn: func(cm *ConcurrentMap, ns *NamespaceMap) {
+				cm.SetIfNotExists("billing:invoice:43", 2)
+				cm.SetMany(map[interface{}]interface{}{"billing:invoice:44": 3, "shipping:order:1": 4})
+				cm.SetManyIfNotExists(map[interface{}]interface{}{"billing:invoice:45": 5})
+				cm.RemoveMany([]interface{}{"billing:invoice:42", "billing:invoice:44"})
+				cm.Remove("billing:invoice:45")
+			},
+			ExpectedItems: map[string]interface{}{"invoice:43": 2},
+			ExpectedMap:   map[interface{}]interface{}{"billing:invoice:43": 2, "shipping:order:1": 4},
+		},
+		{
+			TestAlias: "Nested namespace",
+			Items:     map[interface{}]interface{}{"billing:invoice:42": 1, "billing:refund:1": 2},
+			Prefix:    "billing:",
+			ModifyFn: func(cm *ConcurrentMap, ns *NamespaceMap) {
+				ns.Namespace("invoice:").Set("43", 3)
+				ns.Namespace("refund:").Remove("1")
+			},
+			ExpectedItems: map[string]interface{}{"invoice:42": 1, "invoice:43": 3},
+			ExpectedMap:   map[interface{}]interface{}{"billing:invoice:42": 1, "billing:invoice:43": 3},
+		},
+		{
+			TestAlias:     "Empty prefix",
+			Items:         map[interface{}]interface{}{"a": 1, "b": 2, 123: 3},
+			Prefix:        "",
+			ModifyFn:      func(cm *ConcurrentMap, ns *NamespaceMap) {},
+			ExpectedItems: map[string]interface{}{"a": 1, "b": 2},
+			ExpectedMap:   map[interface{}]interface{}{"a": 1, "b": 2, 123: 3},
+		},
+		{
+			TestAlias:     "Prefix ending with the greatest byte",
+			Items:         map[interface{}]interface{}{"a\xff": 1, "a\xff\xff": 2, "b": 3, "a": 4},
+			Prefix:        "a\xff",
+			ModifyFn:      func(cm *ConcurrentMap, ns *NamespaceMap) {},
+			ExpectedItems: map[string]interface{}{"": 1, "\xff": 2},
+			ExpectedMap:   map[interface{}]interface{}{"a\xff": 1, "a\xff\xff": 2, "b": 3, "a": 4},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		items := testCase.Items
+		prefix := testCase.Prefix
+		modifyFn := testCase.ModifyFn
+		expectedItems := testCase.ExpectedItems
+		expectedMap := testCase.ExpectedMap
+
+		testFn := func(t *testing.T) {
+
+			cm := MakeConcurrentCopy(items)
+			ns := cm.Namespace(prefix)
+
+			modifyFn(cm, ns)
+
+			if actualItems := ns.Items(); !reflect.DeepEqual(actualItems, expectedItems) {
+				t.Errorf("%s :: ns.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+			if actualLen := ns.Len(); actualLen != len(expectedItems) {
+				t.Errorf("%s :: ns.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedItems))
+			}
+			for key, expectedValue := range expectedItems {
+				if actualValue, ok := ns.Get(key); !ok || actualValue != expectedValue {
+					t.Errorf("%s :: ns.Get(%q) returned %v, %v while expected %v, true ", testAlias, key, actualValue, ok, expectedValue)
+				}
+			}
+			if actualMap := cm.Items(); !reflect.DeepEqual(actualMap, expectedMap) {
+				t.Errorf("%s :: cm.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualMap, expectedMap)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestNamespaceConcurrentWrites(t *testing.T) {
+	cm := New(0)
+	for i := 0; i < 1000; i++ {
+		cm.Set(fmt.Sprintf("ns:%d", i), i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				cm.Set(fmt.Sprintf("ns:%d:%d", g, i), i)
+				cm.Remove(fmt.Sprintf("ns:%d", g*250+i/2))
+			}
+		}(g)
+	}
+	var namespaces []*NamespaceMap
+	for g := 0; g < 4; g++ {
+		namespaces = append(namespaces, cm.Namespace("ns:"))
+	}
+	wg.Wait()
+
+	expected := map[string]interface{}{}
+	for key, value := range cm.Items() {
+		expected[strings.TrimPrefix(key.(string), "ns:")] = value
+	}
+	for _, ns := range namespaces {
+		if actual := ns.Items(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("ns.Items() returned %d elements while expected %d ", len(actual), len(expected))
+		}
+	}
+}
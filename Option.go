@@ -0,0 +1,39 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "reflect"
+
+// Represents an option of a ConcurrentMap, applied on construction by NewWithOptions.
+type Option func(*ConcurrentMap)
+
+// Instantiates and initializes ConcurrentMap with `initCap` capacity and given options.
+func NewWithOptions(initCap int, opts ...Option) *ConcurrentMap {
+	cm := New(initCap)
+	for _, opt := range opts {
+		opt(cm)
+	}
+	return cm
+}
+
+// Restricts keys of the map to the exact type `keyType`.
+//
+// TrySet and TrySetIfNotExists return ErrKeyType on a key of any other type, while Set and SetIfNotExists panic with the same error.
+// Get and Remove do not check the type, as a key of another type can never be present.
+func WithKeyType(keyType reflect.Type) Option {
+	return func(cm *ConcurrentMap) {
+		cm.keyType = keyType
+	}
+}
@@ -0,0 +1,83 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestWithKeyType(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		KeyType       reflect.Type
+		Key           interface{}
+		ExpectedError error
+	}{
+		{
+			TestAlias:     "Key of expected type",
+			KeyType:       reflect.TypeOf(""),
+			Key:           "key",
+			ExpectedError: nil,
+		},
+		{
+			TestAlias:     "Key of other type",
+			KeyType:       reflect.TypeOf(""),
+			Key:           123,
+			ExpectedError: ErrKeyType,
+		},
+		{
+			TestAlias:     "Nil key",
+			KeyType:       reflect.TypeOf(""),
+			Key:           nil,
+			ExpectedError: ErrKeyType,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		keyType := testCase.KeyType
+		key := testCase.Key
+		expectedError := testCase.ExpectedError
+
+		testFn := func(t *testing.T) {
+
+			cm := NewWithOptions(0, WithKeyType(keyType))
+
+			if err := cm.TrySet(key, "value"); !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.TrySet(%#v, ...) returned error %v while expected %v ", testAlias, key, err, expectedError)
+			}
+			if _, err := cm.TrySetIfNotExists(key, "value"); !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.TrySetIfNotExists(%#v, ...) returned error %v while expected %v ", testAlias, key, err, expectedError)
+			}
+
+			actualPanic := func() (recovered interface{}) {
+				defer func() { recovered = recover() }()
+				cm.Clone().Set(key, "value")
+				return nil
+			}()
+
+			if err, _ := actualPanic.(error); !errors.Is(err, expectedError) || (actualPanic == nil) != (expectedError == nil) {
+				t.Errorf("%s :: cm.Clone().Set(%#v, ...) panicked with %v while expected %v ", testAlias, key, actualPanic, expectedError)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,190 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "sync"
+
+// The OverlayMap type represents a view resolving keys through a chain of layers, e.g. runtime overrides over defaults.
+//
+// Reads return the value of the highest priority layer holding the key, while writes go to the top layer.
+// Removal of a key masks it in lower layers with a tombstone, kept until the key is set through the overlay again.
+// Layers stay ordinary maps, which can be modified directly as well.
+type OverlayMap struct {
+	// Highest priority first
+	layers     []*ConcurrentMap
+	tombstones map[interface{}]struct{}
+	// Guards tombstones and serializes writes through the overlay
+	lock sync.RWMutex
+	// Rejects writes through the overlay, set for views of nested maps returned by Get
+	readOnly bool
+}
+
+// Instantiates OverlayMap over `layers` given from the highest priority to the lowest.
+// The first layer receives all writes; if no layers are given, an empty one is created.
+func Overlay(layers ...*ConcurrentMap) *OverlayMap {
+	if len(layers) == 0 {
+		layers = []*ConcurrentMap{New(0)}
+	}
+	return &OverlayMap{
+		layers:     append([]*ConcurrentMap(nil), layers...),
+		tombstones: map[interface{}]struct{}{},
+	}
+}
+
+// Returns layers of the overlay, from the highest priority to the lowest.
+func (this *OverlayMap) Layers() []*ConcurrentMap {
+	return append([]*ConcurrentMap(nil), this.layers...)
+}
+
+// Returns true if lower layers are masked for the `key`.
+func (this *OverlayMap) masked(key interface{}) bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	_, ok := this.tombstones[key]
+	return ok
+}
+
+// Retrieves the value under given key from the highest priority layer holding it.
+// Returns false in case no layer holds the key or it was removed through the overlay.
+//
+// If the value is a map, it is returned as a read-only *OverlayMap over it and maps under the same key of lower layers,
+// up to the first layer holding a non-map value. The view is created in O(number of layers) and resolves its keys
+// on demand, so it reflects later modifications of the layers. Its writes panic with ErrFrozen.
+func (this *OverlayMap) Get(key interface{}) (interface{}, bool) {
+	layers := this.layers
+	if this.masked(key) {
+		layers = layers[:1]
+	}
+	values := make([]interface{}, 0, len(layers))
+	for _, layer := range layers {
+		if value, ok := layer.Get(key); ok {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return resolveLayers(values), true
+}
+
+// Panics with ErrFrozen if writes through the overlay are rejected.
+func (this *OverlayMap) mustBeWritable() {
+	if this.readOnly {
+		panic(ErrFrozen)
+	}
+}
+
+// Sets the given value under the specified key of the top layer and unmasks the key in lower layers.
+func (this *OverlayMap) Set(key interface{}, val interface{}) {
+	this.mustBeWritable()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.layers[0].Set(key, val)
+	delete(this.tombstones, key)
+}
+
+// Sets the given value under the specified key of the top layer and returns true, if no layer held the key upon invokation.
+// Returns false and does nothing otherwise.
+func (this *OverlayMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	this.mustBeWritable()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if _, masked := this.tombstones[key]; !masked {
+		for _, layer := range this.layers[1:] {
+			if _, ok := layer.Get(key); ok {
+				return false
+			}
+		}
+	}
+	if !this.layers[0].SetIfNotExists(key, val) {
+		return false
+	}
+	delete(this.tombstones, key)
+	return true
+}
+
+// Removes the key from the top layer and masks it in lower layers.
+func (this *OverlayMap) Remove(key interface{}) {
+	this.mustBeWritable()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.layers[0].Remove(key)
+	this.tombstones[key] = struct{}{}
+}
+
+// Returns merged content of all layers as non concurrent(general) `map[interface{}]interface{}`.
+// Each layer is snapshotted independently. Nested maps are returned the way Get returns them,
+// as read-only *OverlayMap views over the maps of the layers, which reflect later modifications of the layers.
+func (this *OverlayMap) Items() map[interface{}]interface{} {
+	this.lock.RLock()
+	tombstones := make(map[interface{}]struct{}, len(this.tombstones))
+	for key := range this.tombstones {
+		tombstones[key] = struct{}{}
+	}
+	this.lock.RUnlock()
+
+	snapshots := make([]map[interface{}]interface{}, len(this.layers))
+	for i, layer := range this.layers {
+		snapshots[i] = layer.Items()
+		if i > 0 {
+			for key := range tombstones {
+				delete(snapshots[i], key)
+			}
+		}
+	}
+
+	merged := map[interface{}]interface{}{}
+	for i, snapshot := range snapshots {
+		for key := range snapshot {
+			if _, ok := merged[key]; ok {
+				continue
+			}
+			values := []interface{}{}
+			for _, lower := range snapshots[i:] {
+				if value, ok := lower[key]; ok {
+					values = append(values, value)
+				}
+			}
+			merged[key] = resolveLayers(values)
+		}
+	}
+	return merged
+}
+
+// Returns number of keys of the merged content.
+func (this *OverlayMap) Len() int {
+	return len(this.Items())
+}
+
+// Returns the value of the highest priority layer among `values`. If it is a map, returns a read-only view
+// over it and maps of consecutive lower layers.
+func resolveLayers(values []interface{}) interface{} {
+	if top, ok := values[0].(*ConcurrentMap); !ok || top == nil {
+		return values[0]
+	}
+	nested := []*ConcurrentMap{}
+	for _, value := range values {
+		cm, ok := value.(*ConcurrentMap)
+		if !ok || cm == nil {
+			break
+		}
+		nested = append(nested, cm)
+	}
+	return &OverlayMap{layers: nested, readOnly: true}
+}
@@ -0,0 +1,207 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestOverlay(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Layers        []map[interface{}]interface{}
+		ModifyFn      func(om *OverlayMap)
+		ExpectedItems map[interface{}]interface{}
+		ExpectedTop   map[interface{}]interface{}
+	}{
+		{
+			TestAlias: "Higher layer wins",
+			Layers: []map[interface{}]interface{}{
+				{"key1": "override"},
+				{"key1": "file", "key2": 123},
+				{"key1": "default", "key2": 0, "key3": 4.56},
+			},
+			ModifyFn:      func(om *OverlayMap) {},
+			ExpectedItems: map[interface{}]interface{}{"key1": "override", "key2": 123, "key3": 4.56},
+			ExpectedTop:   map[interface{}]interface{}{"key1": "override"},
+		},
+		{
+			TestAlias: "Nested maps are merged",
+			Layers: []map[interface{}]interface{}{
+				{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override"})},
+				{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "default", "key2": 123})},
+			},
+			ModifyFn: func(om *OverlayMap) {},
+			ExpectedItems: map[interface{}]interface{}{
+				"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override", "key2": 123}),
+			},
+			ExpectedTop: map[interface{}]interface{}{
+				"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override"}),
+			},
+		},
+		{
+			TestAlias: "Scalar shadows lower maps",
+			Layers: []map[interface{}]interface{}{
+				{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override"})},
+				{"Map": "disabled"},
+				{"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key2": 123})},
+			},
+			ModifyFn: func(om *OverlayMap) {},
+			ExpectedItems: map[interface{}]interface{}{
+				"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override"}),
+			},
+			ExpectedTop: map[interface{}]interface{}{
+				"Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override"}),
+			},
+		},
+		{
+			TestAlias: "Writes go to the top layer",
+			Layers: []map[interface{}]interface{}{
+				{},
+				{"key1": "default"},
+			},
+			ModifyFn: func(om *OverlayMap) {
+				om.Set("key1", "override")
+				om.SetIfNotExists("key2", 123)
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "override", "key2": 123},
+			ExpectedTop:   map[interface{}]interface{}{"key1": "override", "key2": 123},
+		},
+		{
+			TestAlias: "Removal masks lower layers",
+			Layers: []map[interface{}]interface{}{
+				{"key1": "override"},
+				{"key1": "default", "key2": 123},
+			},
+			ModifyFn: func(om *OverlayMap) {
+				om.Remove("key1")
+				om.Remove("key2")
+			},
+			ExpectedItems: map[interface{}]interface{}{},
+			ExpectedTop:   map[interface{}]interface{}{},
+		},
+		{
+			TestAlias: "Set unmasks the key",
+			Layers: []map[interface{}]interface{}{
+				{},
+				{"key1": "default"},
+			},
+			ModifyFn: func(om *OverlayMap) {
+				om.Remove("key1")
+				om.SetIfNotExists("key1", "override")
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "override"},
+			ExpectedTop:   map[interface{}]interface{}{"key1": "override"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		layers := testCase.Layers
+		modifyFn := testCase.ModifyFn
+		expectedItems := testCase.ExpectedItems
+		expectedTop := testCase.ExpectedTop
+
+		testFn := func(t *testing.T) {
+
+			cms := make([]*ConcurrentMap, len(layers))
+			for i, layer := range layers {
+				cms[i] = MakeConcurrentCopy(layer)
+			}
+			om := Overlay(cms...)
+
+			modifyFn(om)
+
+			actualItems := resolvedItems(om)
+			if !MakeConcurrentCopy(actualItems).Equal(MakeConcurrentCopy(expectedItems)) {
+				t.Errorf("%s :: om.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+			if actualLen := om.Len(); actualLen != len(expectedItems) {
+				t.Errorf("%s :: om.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedItems))
+			}
+			for key, expectedValue := range expectedItems {
+				actualValue, ok := om.Get(key)
+				if view, isView := actualValue.(*OverlayMap); isView {
+					actualValue = MakeConcurrentCopy(resolvedItems(view))
+				}
+				if !ok || !MakeConcurrentCopy(map[interface{}]interface{}{key: actualValue}).Equal(MakeConcurrentCopy(map[interface{}]interface{}{key: expectedValue})) {
+					t.Errorf("%s :: om.Get(%#v) returned %#v, %v while expected %#v, true ", testAlias, key, actualValue, ok, expectedValue)
+				}
+			}
+			if actualTop := om.Layers()[0].Items(); !MakeConcurrentCopy(actualTop).Equal(MakeConcurrentCopy(expectedTop)) {
+				t.Errorf("%s :: top layer holds \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualTop, expectedTop)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+// Returns items of the `om` with nested views replaced by their resolved content.
+func resolvedItems(om *OverlayMap) map[interface{}]interface{} {
+	items := om.Items()
+	for key, value := range items {
+		if view, ok := value.(*OverlayMap); ok {
+			items[key] = MakeConcurrentCopy(resolvedItems(view))
+		}
+	}
+	return items
+}
+
+func TestOverlaySelfReferencingLayer(t *testing.T) {
+
+	cm := MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue"})
+	cm.Set("Self", cm)
+	om := Overlay(cm, New(0))
+
+	if actualItems := om.Items(); !reflect.DeepEqual(actualItems["key1"], "stringValue") {
+		t.Errorf("om.Items() returned \r\n %#v \r\n while expected key1 to be resolved ", actualItems)
+	}
+
+}
+
+func TestOverlayNestedView(t *testing.T) {
+
+	top := MakeConcurrentCopy(map[interface{}]interface{}{"key1": "override"})
+	bottom := MakeConcurrentCopy(map[interface{}]interface{}{"key1": "default", "key2": 123})
+	om := Overlay(MakeConcurrentCopy(map[interface{}]interface{}{"Map": top}), MakeConcurrentCopy(map[interface{}]interface{}{"Map": bottom}))
+
+	value, _ := om.Get("Map")
+	view, ok := value.(*OverlayMap)
+	if !ok {
+		t.Fatalf("om.Get(\"Map\") returned %#v while expected *OverlayMap ", value)
+	}
+	if fromItems, ok := om.Items()["Map"].(*OverlayMap); !ok || !reflect.DeepEqual(fromItems.Layers(), view.Layers()) {
+		t.Errorf("om.Items()[\"Map\"] returned %#v while expected the same view as om.Get(\"Map\") ", om.Items()["Map"])
+	}
+	bottom.Set("key3", 4.56)
+	if actual, _ := view.Get("key3"); actual != 4.56 {
+		t.Errorf("view.Get(\"key3\") returned %v while expected the later modification of a layer 4.56 ", actual)
+	}
+	if actual, _ := view.Get("key1"); actual != "override" {
+		t.Errorf("view.Get(\"key1\") returned %v while expected override ", actual)
+	}
+
+	defer func() {
+		if r := recover(); r != ErrFrozen {
+			t.Errorf("view.Set() panicked with %v while expected ErrFrozen ", r)
+		}
+	}()
+	view.Set("key1", "lost")
+}
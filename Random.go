@@ -0,0 +1,85 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "math/rand/v2"
+
+// Returns a uniformly random key of the map, or false if the map is empty.
+//
+// Keys are picked by position of the index shared with Scan, so it costs O(1) and copies nothing once the index is built.
+// Unless the map was created WithScanIndex, the first pick builds the index, copying all keys under the read lock once.
+// The index costs about as much memory as a copy made by Items and is kept until ReleaseScanIndex.
+func (this *ConcurrentMap) RandomKey() (interface{}, bool) {
+	entry, ok := this.RandomEntry()
+	return entry.Key, ok
+}
+
+// Returns a uniformly random element of the map, or false if the map is empty.
+func (this *ConcurrentMap) RandomEntry() (Entry, bool) {
+	this.readLockDense()
+	defer this.lock.RUnlock()
+
+	if len(this.dense.keys) == 0 {
+		return Entry{}, false
+	}
+	key := this.dense.keys[rand.IntN(len(this.dense.keys))]
+	return Entry{Key: key, Value: this.items[key]}, true
+}
+
+// Returns `n` uniformly random elements of the map, in no particular order, from a consistent snapshot.
+//
+// With replacement, elements are drawn independently, so the same one may be returned several times and
+// a non-empty map always yields `n` elements. Without replacement, every subset of `n` distinct elements is
+// equally likely, picked with Floyd's algorithm in O(n); all elements are returned if the map holds no more than `n`.
+func (this *ConcurrentMap) Sample(n int, withReplacement bool) []Entry {
+	this.readLockDense()
+	defer this.lock.RUnlock()
+
+	size := len(this.dense.keys)
+	if n <= 0 || size == 0 {
+		return []Entry{}
+	}
+	entryAt := func(i int) Entry {
+		key := this.dense.keys[i]
+		return Entry{Key: key, Value: this.items[key]}
+	}
+
+	if withReplacement {
+		entries := make([]Entry, n)
+		for i := range entries {
+			entries[i] = entryAt(rand.IntN(size))
+		}
+		return entries
+	}
+
+	if n >= size {
+		entries := make([]Entry, size)
+		for i := range entries {
+			entries[i] = entryAt(i)
+		}
+		return entries
+	}
+	entries := make([]Entry, 0, n)
+	picked := make(map[int]bool, n)
+	for j := size - n; j < size; j++ {
+		i := rand.IntN(j + 1)
+		if picked[i] {
+			i = j
+		}
+		picked[i] = true
+		entries = append(entries, entryAt(i))
+	}
+	return entries
+}
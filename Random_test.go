@@ -0,0 +1,174 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+// Represents critical value of chi-square distribution with 9 degrees of freedom for p = 0.0001,
+// so a uniform sampler fails the test once in 10000 runs.
+const chiSquare9Critical = 33.72
+
+// Returns chi-square statistic of `observed` counts against the same `expected` count for every category.
+func chiSquare(observed map[interface{}]int, categories int, expected float64) float64 {
+	x := 0.0
+	for i := 0; i < categories; i++ {
+		d := float64(observed[i]) - expected
+		x += d * d / expected
+	}
+	return x
+}
+
+func TestRandomUniformity(t *testing.T) {
+
+	const draws = 100000
+
+	testCases := []struct {
+		TestAlias string
+		// Draws keys and returns the number each key was drawn and the expected number
+		DrawFn func(cm *ConcurrentMap) (map[interface{}]int, float64)
+	}{
+		{
+			TestAlias: "RandomKey",
+			DrawFn: func(cm *ConcurrentMap) (map[interface{}]int, float64) {
+				observed := map[interface{}]int{}
+				for i := 0; i < draws; i++ {
+					key, _ := cm.RandomKey()
+					observed[key]++
+				}
+				return observed, draws / 10
+			},
+		},
+		{
+			TestAlias: "RandomEntry with the index released",
+			DrawFn: func(cm *ConcurrentMap) (map[interface{}]int, float64) {
+				observed := map[interface{}]int{}
+				for i := 0; i < draws; i++ {
+					if i%1000 == 0 {
+						cm.ReleaseScanIndex()
+					}
+					entry, _ := cm.RandomEntry()
+					observed[entry.Key]++
+				}
+				return observed, draws / 10
+			},
+		},
+		{
+			TestAlias: "Sample with replacement",
+			DrawFn: func(cm *ConcurrentMap) (map[interface{}]int, float64) {
+				observed := map[interface{}]int{}
+				for i := 0; i < draws/5; i++ {
+					for _, entry := range cm.Sample(5, true) {
+						observed[entry.Key]++
+					}
+				}
+				return observed, draws / 10
+			},
+		},
+		{
+			TestAlias: "Sample without replacement",
+			DrawFn: func(cm *ConcurrentMap) (map[interface{}]int, float64) {
+				observed := map[interface{}]int{}
+				for i := 0; i < draws/3; i++ {
+					for _, entry := range cm.Sample(3, false) {
+						observed[entry.Key]++
+					}
+				}
+				return observed, float64(draws/3) * 3 / 10
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		drawFn := testCase.DrawFn
+
+		testFn := func(t *testing.T) {
+
+			// Removals move keys around the index, so the test covers it as well
+			cm := MakeConcurrentCopy(intItems(0, 15))
+			cm.Scan(0, 1)
+			for key := 10; key < 15; key++ {
+				cm.Remove(key)
+			}
+
+			observed, expected := drawFn(cm)
+
+			if len(observed) != 10 {
+				t.Errorf("%s :: drew %d distinct keys while expected 10: %v ", testAlias, len(observed), observed)
+			}
+			if x := chiSquare(observed, 10, expected); x > chiSquare9Critical {
+				t.Errorf("%s :: chi-square statistic %.2f exceeds %.2f, counts %v ", testAlias, x, chiSquare9Critical, observed)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestSample(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias       string
+		Items           map[interface{}]interface{}
+		N               int
+		WithReplacement bool
+		ExpectedLen     int
+	}{
+		{TestAlias: "Empty map", Items: map[interface{}]interface{}{}, N: 3, WithReplacement: false, ExpectedLen: 0},
+		{TestAlias: "Empty map with replacement", Items: map[interface{}]interface{}{}, N: 3, WithReplacement: true, ExpectedLen: 0},
+		{TestAlias: "Non-positive n", Items: intItems(0, 5), N: 0, WithReplacement: false, ExpectedLen: 0},
+		{TestAlias: "More than size", Items: intItems(0, 5), N: 10, WithReplacement: false, ExpectedLen: 5},
+		{TestAlias: "More than size with replacement", Items: intItems(0, 5), N: 10, WithReplacement: true, ExpectedLen: 10},
+		{TestAlias: "Less than size", Items: intItems(0, 100), N: 10, WithReplacement: false, ExpectedLen: 10},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		items := testCase.Items
+		n := testCase.N
+		withReplacement := testCase.WithReplacement
+		expectedLen := testCase.ExpectedLen
+
+		testFn := func(t *testing.T) {
+
+			cm := MakeConcurrentCopy(items)
+
+			entries := cm.Sample(n, withReplacement)
+
+			if len(entries) != expectedLen {
+				t.Errorf("%s :: cm.Sample(%d, %v) returned %d entries while expected %d ", testAlias, n, withReplacement, len(entries), expectedLen)
+			}
+			seen := map[interface{}]bool{}
+			for _, entry := range entries {
+				if items[entry.Key] != entry.Value {
+					t.Errorf("%s :: cm.Sample(...) returned entry %#v not present in the map ", testAlias, entry)
+				}
+				if seen[entry.Key] && !withReplacement {
+					t.Errorf("%s :: cm.Sample(...) returned key %#v twice without replacement ", testAlias, entry.Key)
+				}
+				seen[entry.Key] = true
+			}
+			if _, ok := cm.RandomEntry(); ok != (len(items) > 0) {
+				t.Errorf("%s :: cm.RandomEntry() returned %v while map holds %d items ", testAlias, ok, len(items))
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
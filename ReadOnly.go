@@ -0,0 +1,58 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+// The ReadOnlyMap type represents a view of a ConcurrentMap providing read methods only.
+//
+// The view reflects changes made to the underlying map after it was created. Nested maps are returned as they are,
+// so use Freeze to protect a whole tree from modifications.
+type ReadOnlyMap struct {
+	cm *ConcurrentMap
+}
+
+// Returns read-only view of the map.
+func (this *ConcurrentMap) ReadOnly() *ReadOnlyMap {
+	return &ReadOnlyMap{cm: this}
+}
+
+// Retrieves an element from the underlying map under given key, see ConcurrentMap.Get.
+func (this *ReadOnlyMap) Get(key interface{}) (interface{}, bool) {
+	return this.cm.Get(key)
+}
+
+// Retrieves an element from the underlying map under given key, see ConcurrentMap.TryGet.
+func (this *ReadOnlyMap) TryGet(key interface{}) (interface{}, bool, error) {
+	return this.cm.TryGet(key)
+}
+
+// Retrieves elements from the underlying map under given keys, see ConcurrentMap.GetMany.
+func (this *ReadOnlyMap) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	return this.cm.GetMany(keys)
+}
+
+// Returns copy of content of the underlying map as non concurrent(general) `map[interface{}]interface{}`.
+func (this *ReadOnlyMap) Items() map[interface{}]interface{} {
+	return this.cm.Items()
+}
+
+// Returns number of elements in the underlying map.
+func (this *ReadOnlyMap) Len() int {
+	return this.cm.Len()
+}
+
+// Returns a shallow copy of the underlying map, which can be modified, see ConcurrentMap.Clone.
+func (this *ReadOnlyMap) Clone() *ConcurrentMap {
+	return this.cm.Clone()
+}
@@ -0,0 +1,55 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestReadOnly(t *testing.T) {
+
+	cm := MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue"})
+	view := cm.ReadOnly()
+
+	cm.Set("key2", 123)
+	cm.Remove("key1")
+
+	expectedItems := map[interface{}]interface{}{"key2": 123}
+	if actualItems := view.Items(); !reflect.DeepEqual(actualItems, expectedItems) {
+		t.Errorf("view.Items() returned \r\n %#v \r\n while expected \r\n %#v ", actualItems, expectedItems)
+	}
+	if actualLen := view.Len(); actualLen != 1 {
+		t.Errorf("view.Len() returned %d while expected 1 ", actualLen)
+	}
+	if actualValue, ok := view.Get("key2"); actualValue != 123 || !ok {
+		t.Errorf("view.Get(\"key2\") returned %v, %v while expected 123, true ", actualValue, ok)
+	}
+	if _, ok, err := view.TryGet("key1"); ok || err != nil {
+		t.Errorf("view.TryGet(\"key1\") returned %v, %v while expected false, nil ", ok, err)
+	}
+	if found, missing := view.GetMany([]interface{}{"key1", "key2"}); !reflect.DeepEqual(found, expectedItems) || !reflect.DeepEqual(missing, []interface{}{"key1"}) {
+		t.Errorf("view.GetMany(...) returned %#v, %#v ", found, missing)
+	}
+
+	clone := view.Clone()
+	clone.Set("key3", 4.56)
+	if _, ok := view.Get("key3"); ok {
+		t.Errorf("modification of view.Clone() is visible through the view ")
+	}
+
+}
@@ -0,0 +1,190 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+// Default values
+const (
+	// Represents default number of entries returned by Scan
+	DEFAULT_SCANCOUNT = 10
+)
+
+// Represents keys of a map by position, so that they can be visited incrementally or picked at random.
+// Removal moves the last key into the freed position.
+type denseIndex struct {
+	keys  []interface{}
+	index map[interface{}]int
+	// Changes made while the index is being built, in order, see buildDenseIndex
+	journal  []denseChange
+	building bool
+}
+
+type denseChange struct {
+	key     interface{}
+	removed bool
+}
+
+// Returns the index of `keys`, which must be distinct, taking ownership of the slice.
+func newDenseIndex(keys []interface{}) *denseIndex {
+	dense := &denseIndex{keys: keys, index: make(map[interface{}]int, len(keys))}
+	for i, key := range keys {
+		dense.index[key] = i
+	}
+	return dense
+}
+
+// Appends the `key`, unless it is already indexed.
+func (this *denseIndex) add(key interface{}) {
+	if this.building {
+		this.journal = append(this.journal, denseChange{key: key})
+		return
+	}
+	if _, ok := this.index[key]; !ok {
+		this.index[key] = len(this.keys)
+		this.keys = append(this.keys, key)
+	}
+}
+
+// Removes the `key`, if indexed, moving the last key into its position.
+func (this *denseIndex) remove(key interface{}) {
+	if this.building {
+		this.journal = append(this.journal, denseChange{key: key, removed: true})
+		return
+	}
+	i, ok := this.index[key]
+	if !ok {
+		return
+	}
+	last := len(this.keys) - 1
+	if i != last {
+		this.keys[i] = this.keys[last]
+		this.index[this.keys[i]] = i
+	}
+	this.keys[last] = nil
+	this.keys = this.keys[:last]
+	delete(this.index, key)
+}
+
+// Makes the map keep the positional index of keys used by Scan, RandomKey, RandomEntry and Sample from construction on,
+// so that not even the first of those calls copies keys under the lock, stalling writers of a big map.
+//
+// The index holds every key in a slice and in a map, so it costs about as much memory as the copy an export through Items
+// makes, except that it is kept for the life of the map, and every write pays for maintaining it.
+// ReleaseScanIndex still drops the index, which the next of those calls then builds the usual way.
+func WithScanIndex() Option {
+	return func(cm *ConcurrentMap) {
+		cm.scanIndex = true
+		keys := make([]interface{}, 0, len(cm.items))
+		for key := range cm.items {
+			keys = append(keys, key)
+		}
+		cm.dense = newDenseIndex(keys)
+	}
+}
+
+// Read-locks the map, building the dense index first if needed.
+func (this *ConcurrentMap) readLockDense() {
+	for {
+		this.readLock()
+		if this.dense != nil && !this.dense.building {
+			return
+		}
+		this.lock.RUnlock()
+		this.buildDenseIndex()
+	}
+}
+
+// Builds the dense index without blocking writers for longer than copying keys takes: keys are collected under the read lock
+// and indexed with no lock held, while writes made meanwhile are journaled and replayed under the write lock once it is ready.
+func (this *ConcurrentMap) buildDenseIndex() {
+	this.denseLock.Lock()
+	defer this.denseLock.Unlock()
+
+	this.writeLock()
+	if this.dense != nil {
+		this.lock.Unlock()
+		return
+	}
+	pending := &denseIndex{building: true}
+	this.dense = pending
+	this.lock.Unlock()
+
+	this.readLock()
+	keys := make([]interface{}, 0, len(this.items))
+	for key := range this.items {
+		keys = append(keys, key)
+	}
+	this.lock.RUnlock()
+
+	dense := newDenseIndex(keys)
+
+	this.writeLock()
+	defer this.lock.Unlock()
+
+	// Changes made before the keys were collected are replayed as well, which is harmless since every change
+	// sets the final presence of its key
+	for _, change := range pending.journal {
+		if change.removed {
+			dense.remove(change.key)
+		} else {
+			dense.add(change.key)
+		}
+	}
+	this.dense = dense
+}
+
+// Drops the positional index of keys built by WithScanIndex or the first Scan, RandomKey, RandomEntry or Sample, freeing its memory,
+// so that writes no longer maintain it. The next of those calls builds the index anew, so cursors of scans in progress
+// are no longer valid: such scans may miss keys and should be restarted.
+func (this *ConcurrentMap) ReleaseScanIndex() {
+	this.denseLock.Lock()
+	defer this.denseLock.Unlock()
+
+	this.writeLock()
+	defer this.lock.Unlock()
+
+	this.dense = nil
+}
+
+// Returns up to `count` entries starting at `cursor` and the cursor to continue from.
+// Scanning starts with cursor 0 and is complete once the returned cursor is 0 again.
+//
+// Every key present in the map during the whole scan is returned at least once, though it may be returned more than once,
+// and keys set or removed in between calls may or may not be returned. Each call holds the read lock only for its batch,
+// so writers proceed in between. Non-positive `count` means DEFAULT_SCANCOUNT.
+//
+// The first scan or sampling of the map builds a positional index of keys, blocking writers only while keys are copied,
+// unless the map was created WithScanIndex. It costs a slice and a map of all keys, about as much memory as a copy made
+// by Items, and every write maintains it from then on, until ReleaseScanIndex drops it.
+// Cursors are positions in the index: keys are visited from the last position down, and since a removal only moves
+// the last key, which has been visited already, no key present during the whole scan can skip it.
+func (this *ConcurrentMap) Scan(cursor uint64, count int) ([]Entry, uint64) {
+	if count <= 0 {
+		count = DEFAULT_SCANCOUNT
+	}
+	this.readLockDense()
+	defer this.lock.RUnlock()
+
+	// Cursor holds the number of positions left to visit
+	left := uint64(len(this.dense.keys))
+	if cursor != 0 && cursor < left {
+		left = cursor
+	}
+	entries := make([]Entry, 0, min(uint64(count), left))
+	for ; left > 0 && len(entries) < count; left-- {
+		key := this.dense.keys[left-1]
+		entries = append(entries, Entry{Key: key, Value: this.items[key]})
+	}
+	return entries, left
+}
@@ -0,0 +1,216 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestScan(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias string
+		Items     map[interface{}]interface{}
+		Count     int
+	}{
+		{
+			TestAlias: "Empty map",
+			Items:     map[interface{}]interface{}{},
+			Count:     10,
+		},
+		{
+			TestAlias: "Single batch",
+			Items:     map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56},
+			Count:     10,
+		},
+		{
+			TestAlias: "Many batches",
+			Items:     intItems(0, 1000),
+			Count:     7,
+		},
+		{
+			TestAlias: "Default count",
+			Items:     intItems(0, 100),
+			Count:     0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		items := testCase.Items
+		count := testCase.Count
+
+		testFn := func(t *testing.T) {
+
+			cm := MakeConcurrentCopy(items)
+
+			actualItems := map[interface{}]interface{}{}
+			calls := 0
+			for cursor := uint64(0); ; {
+				var entries []Entry
+				entries, cursor = cm.Scan(cursor, count)
+				calls++
+				if (count > 0 && len(entries) > count) || (count <= 0 && len(entries) > DEFAULT_SCANCOUNT) {
+					t.Errorf("%s :: cm.Scan(...) returned %d entries while count is %d ", testAlias, len(entries), count)
+				}
+				for _, entry := range entries {
+					if _, ok := actualItems[entry.Key]; ok {
+						t.Errorf("%s :: cm.Scan(...) returned key %#v twice on unmodified map ", testAlias, entry.Key)
+					}
+					actualItems[entry.Key] = entry.Value
+				}
+				if cursor == 0 {
+					break
+				}
+			}
+
+			if !reflect.DeepEqual(actualItems, items) {
+				t.Errorf("%s :: scan returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, items)
+			}
+			if calls > len(items)+1 {
+				t.Errorf("%s :: scan took %d calls for %d items ", testAlias, calls, len(items))
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestScanWithModifications(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 20; round++ {
+		// Keys 0..499 stay during the whole scan, keys from 1000 on come and go
+		cm := MakeConcurrentCopy(intItems(0, 500))
+		cm.SetMany(intItems(1000, 1500))
+
+		seen := map[interface{}]bool{}
+		for cursor := uint64(0); ; {
+			var entries []Entry
+			entries, cursor = cm.Scan(cursor, 1+rnd.Intn(20))
+			for _, entry := range entries {
+				seen[entry.Key] = true
+			}
+			if cursor == 0 {
+				break
+			}
+			for i := 0; i < 10; i++ {
+				key := 1000 + rnd.Intn(1000)
+				if rnd.Intn(2) == 0 {
+					cm.Remove(key)
+				} else {
+					cm.Set(key, key)
+				}
+			}
+		}
+
+		for key := 0; key < 500; key++ {
+			if !seen[key] {
+				t.Fatalf("round %d :: scan missed key %d present during the whole scan ", round, key)
+			}
+		}
+	}
+
+}
+
+func TestScanIndexBuiltDuringWrites(t *testing.T) {
+
+	cm := MakeConcurrentCopy(intItems(0, 1000))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				cm.Set(1000+g*250+i, i)
+				cm.Remove(g*250 + i)
+			}
+		}(g)
+	}
+	for g := 0; g < 4; g++ {
+		cm.Scan(0, 1)
+		cm.ReleaseScanIndex()
+	}
+	cm.Scan(0, 1)
+	wg.Wait()
+
+	seen := map[interface{}]interface{}{}
+	for cursor := uint64(0); ; {
+		var entries []Entry
+		entries, cursor = cm.Scan(cursor, 100)
+		for _, entry := range entries {
+			if _, ok := seen[entry.Key]; ok {
+				t.Errorf("scan returned key %v twice without modifications ", entry.Key)
+			}
+			seen[entry.Key] = entry.Value
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	if expected := cm.Items(); !reflect.DeepEqual(seen, expected) {
+		t.Errorf("scan returned %d entries while the map holds %d ", len(seen), len(expected))
+	}
+
+}
+
+func TestWithScanIndex(t *testing.T) {
+
+	lazy := MakeConcurrentCopy(intItems(0, 100000))
+	eager := NewWithOptions(0, WithScanIndex())
+	eager.SetMany(intItems(0, 100000))
+
+	allocatedByFirstScan := func(cm *ConcurrentMap) uint64 {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		cm.Scan(0, 1)
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+	if lazyBytes, eagerBytes := allocatedByFirstScan(lazy), allocatedByFirstScan(eager); eagerBytes*100 > lazyBytes {
+		t.Errorf("first Scan allocated %d bytes WithScanIndex while expected far less than %d bytes of building the index ", eagerBytes, lazyBytes)
+	}
+
+	clone := eager.Clone()
+	clone.Remove(0)
+	seen := map[interface{}]bool{}
+	for cursor := uint64(0); ; {
+		var entries []Entry
+		entries, cursor = clone.Scan(cursor, 1000)
+		for _, entry := range entries {
+			seen[entry.Key] = true
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 99999 || seen[0] {
+		t.Errorf("clone.Scan(...) visited %d keys while expected 99999 without the removed one ", len(seen))
+	}
+
+	eager.ReleaseScanIndex()
+	if sample := eager.Sample(10, false); len(sample) != 10 {
+		t.Errorf("eager.Sample(10, false) returned %d elements after ReleaseScanIndex while expected 10 ", len(sample))
+	}
+
+}
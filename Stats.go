@@ -0,0 +1,154 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Represents number of buckets of the lock wait histogram.
+// The first bucket counts waits shorter than a microsecond, every next one doubles the upper bound and the last one is unbounded.
+const LOCKWAIT_BUCKETS = 20
+
+// Represents a point-in-time snapshot of statistics of a ConcurrentMap, see WithStats.
+//
+// Counters are updated independently, so a snapshot taken concurrently with operations may be off by in-flight operations.
+type Stats struct {
+	// Number of looked up keys, by Get and GetMany
+	Gets uint64 `json:"gets"`
+	// Number of looked up keys which were present
+	Hits uint64 `json:"hits"`
+	// Number of looked up keys which were absent
+	Misses uint64 `json:"misses"`
+	// Number of stored values, by Set, SetIfNotExists and their batch counterparts
+	Sets uint64 `json:"sets"`
+	// Number of keys requested to be removed, by Remove and RemoveMany
+	Removes uint64 `json:"removes"`
+	// Number of elements in the map
+	Size int `json:"size"`
+	// Histogram of time spent waiting for the lock of the map
+	LockWait LockWaitHistogram `json:"lockWait"`
+}
+
+// Represents histogram of lock wait durations with exponential buckets.
+type LockWaitHistogram struct {
+	// Counts[i] is the number of waits not longer than Bounds[i] and longer than Bounds[i-1]
+	Counts [LOCKWAIT_BUCKETS]uint64 `json:"counts"`
+	// Total number of lock acquisitions
+	Count uint64 `json:"count"`
+	// Total time spent waiting, rendered to JSON in nanoseconds
+	Sum time.Duration `json:"sum"`
+}
+
+// Returns inclusive upper bound of the i-th bucket of the histogram, the last bucket has no bound and returns a negative duration.
+func (LockWaitHistogram) Bound(i int) time.Duration {
+	if i >= LOCKWAIT_BUCKETS-1 {
+		return -1
+	}
+	return time.Microsecond << i
+}
+
+// Holds counters of a map with enabled statistics.
+type mapStats struct {
+	gets, hits, misses, sets, removes atomic.Uint64
+	lockWaits                         [LOCKWAIT_BUCKETS]atomic.Uint64
+	lockWaitSum                       atomic.Int64
+}
+
+// Enables collection of statistics of the map, see Stats.
+//
+// Without this option the map only pays for a nil check per operation.
+// With it, operations update atomic counters and lock acquisitions which could not succeed immediately are timed.
+// Clones of the map collect their own statistics from scratch.
+func WithStats() Option {
+	return func(cm *ConcurrentMap) {
+		cm.stats = &mapStats{}
+	}
+}
+
+// Returns a snapshot of statistics of the map and true, or false if the map was not created WithStats.
+func (this *ConcurrentMap) Stats() (Stats, bool) {
+	if this.stats == nil {
+		return Stats{}, false
+	}
+	stats := Stats{
+		Gets:    this.stats.gets.Load(),
+		Hits:    this.stats.hits.Load(),
+		Misses:  this.stats.misses.Load(),
+		Sets:    this.stats.sets.Load(),
+		Removes: this.stats.removes.Load(),
+		Size:    this.Len(),
+	}
+	for i := range this.stats.lockWaits {
+		stats.LockWait.Counts[i] = this.stats.lockWaits[i].Load()
+		stats.LockWait.Count += stats.LockWait.Counts[i]
+	}
+	stats.LockWait.Sum = time.Duration(this.stats.lockWaitSum.Load())
+	return stats, true
+}
+
+// Acquires the lock of the map for writing, recording the wait if statistics are enabled.
+func (this *ConcurrentMap) writeLock() {
+	if this.stats == nil {
+		this.lock.Lock()
+		return
+	}
+	if this.lock.TryLock() {
+		this.stats.observeLockWait(0)
+		return
+	}
+	start := time.Now()
+	this.lock.Lock()
+	this.stats.observeLockWait(time.Since(start))
+}
+
+// Acquires the lock of the map for reading, recording the wait if statistics are enabled.
+func (this *ConcurrentMap) readLock() {
+	if this.stats == nil {
+		this.lock.RLock()
+		return
+	}
+	if this.lock.TryRLock() {
+		this.stats.observeLockWait(0)
+		return
+	}
+	start := time.Now()
+	this.lock.RLock()
+	this.stats.observeLockWait(time.Since(start))
+}
+
+func (this *mapStats) observeLockWait(wait time.Duration) {
+	i := 0
+	if wait > time.Microsecond {
+		// The bit length of ceil(wait / 1µs) - 1 is the exponent of the smallest power of two bound holding the wait
+		i = bits.Len64(uint64((wait - 1) / time.Microsecond))
+		if i >= LOCKWAIT_BUCKETS {
+			i = LOCKWAIT_BUCKETS - 1
+		}
+	}
+	this.lockWaits[i].Add(1)
+	this.lockWaitSum.Add(int64(wait))
+}
+
+func (this *mapStats) observeGet(ok bool) {
+	this.gets.Add(1)
+	if ok {
+		this.hits.Add(1)
+	} else {
+		this.misses.Add(1)
+	}
+}
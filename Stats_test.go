@@ -0,0 +1,174 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestStats(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		OperationsFn  func(cm *ConcurrentMap)
+		ExpectedStats Stats
+		ExpectedLocks uint64
+	}{
+		{
+			TestAlias:     "No operations",
+			OperationsFn:  func(cm *ConcurrentMap) {},
+			ExpectedStats: Stats{},
+			ExpectedLocks: 0,
+		},
+		{
+			TestAlias: "Single key operations",
+			OperationsFn: func(cm *ConcurrentMap) {
+				cm.Set("key1", "stringValue")
+				cm.SetIfNotExists("key1", "otherValue")
+				cm.SetIfNotExists("key2", 123)
+				cm.Get("key1")
+				cm.Get("missingKey")
+				cm.Remove("key2")
+				cm.Remove("missingKey")
+			},
+			ExpectedStats: Stats{Gets: 2, Hits: 1, Misses: 1, Sets: 2, Removes: 2, Size: 1},
+			ExpectedLocks: 7,
+		},
+		{
+			TestAlias: "Batch operations",
+			OperationsFn: func(cm *ConcurrentMap) {
+				cm.SetMany(map[interface{}]interface{}{"key1": "stringValue", "key2": 123})
+				cm.SetManyIfNotExists(map[interface{}]interface{}{"key2": 321, "key3": 4.56})
+				cm.GetMany([]interface{}{"key1", "key2", "missingKey"})
+				cm.RemoveMany([]interface{}{"key3"})
+			},
+			ExpectedStats: Stats{Gets: 3, Hits: 2, Misses: 1, Sets: 3, Removes: 1, Size: 2},
+			ExpectedLocks: 4,
+		},
+		{
+			TestAlias: "Checked operations",
+			OperationsFn: func(cm *ConcurrentMap) {
+				cm.TrySet("key1", "stringValue")
+				cm.TrySet([]int{1}, "stringValue")
+				cm.TryGet("key1")
+			},
+			ExpectedStats: Stats{Gets: 1, Hits: 1, Sets: 1, Size: 1},
+			ExpectedLocks: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		operationsFn := testCase.OperationsFn
+		expectedStats := testCase.ExpectedStats
+		expectedLocks := testCase.ExpectedLocks
+
+		testFn := func(t *testing.T) {
+
+			cm := NewWithOptions(0, WithStats())
+
+			operationsFn(cm)
+			actualStats, ok := cm.Stats()
+
+			if !ok {
+				t.Fatalf("%s :: cm.Stats() returned false while expected true ", testAlias)
+			}
+			lockWait := actualStats.LockWait
+			actualStats.LockWait = LockWaitHistogram{}
+			if actualStats != expectedStats {
+				t.Errorf("%s :: cm.Stats() returned \r\n %+v \r\n while expected \r\n %+v ", testAlias, actualStats, expectedStats)
+			}
+			// Stats() takes the lock itself to get the size
+			if lockWait.Count != expectedLocks+1 {
+				t.Errorf("%s :: cm.Stats() returned %d lock acquisitions while expected %d ", testAlias, lockWait.Count, expectedLocks+1)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestStatsDisabled(t *testing.T) {
+
+	cm := New(0)
+	cm.Set("key1", "stringValue")
+
+	if _, ok := cm.Stats(); ok {
+		t.Errorf("cm.Stats() returned true for map without WithStats ")
+	}
+	if actualLen := cm.Len(); actualLen != 1 {
+		t.Errorf("cm.Len() returned %d while expected 1 ", actualLen)
+	}
+
+}
+
+func TestStatsOfClone(t *testing.T) {
+
+	cm := NewWithOptions(0, WithStats())
+	cm.Set("key1", "stringValue")
+
+	clone := cm.Clone()
+	actualStats, ok := clone.Stats()
+
+	if !ok {
+		t.Fatalf("clone.Stats() returned false while expected true ")
+	}
+	if actualStats.Sets != 0 || actualStats.Size != 1 {
+		t.Errorf("clone.Stats() returned %+v while expected fresh counters and size 1 ", actualStats)
+	}
+
+}
+
+func TestStatsLockWaitHistogram(t *testing.T) {
+
+	cm := NewWithOptions(0, WithStats())
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				cm.Set(g, i)
+				cm.Get(g)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	actualStats, _ := cm.Stats()
+	lockWait := actualStats.LockWait
+
+	if lockWait.Count != 16001 {
+		t.Errorf("cm.Stats() returned %d lock acquisitions while expected 16001 ", lockWait.Count)
+	}
+	if lockWait.Sum < 0 {
+		t.Errorf("cm.Stats() returned negative total lock wait %v ", lockWait.Sum)
+	}
+	if bound := lockWait.Bound(0); bound != time.Microsecond {
+		t.Errorf("lockWait.Bound(0) returned %v while expected %v ", bound, time.Microsecond)
+	}
+	if bound := lockWait.Bound(3); bound != 8*time.Microsecond {
+		t.Errorf("lockWait.Bound(3) returned %v while expected %v ", bound, 8*time.Microsecond)
+	}
+	if bound := lockWait.Bound(LOCKWAIT_BUCKETS - 1); bound >= 0 {
+		t.Errorf("lockWait.Bound(LOCKWAIT_BUCKETS - 1) returned %v while expected negative ", bound)
+	}
+
+}
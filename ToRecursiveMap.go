@@ -0,0 +1,146 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Converts the map and all nested *ConcurrentMap values into general `map[interface{}]interface{}`.
+//
+// It is the inverse of MakeRecursivelyConcurrentCopy. Maps inside `[]interface{}` are converted as well
+// and `[]*ConcurrentMap` becomes `[]map[interface{}]interface{}`. Any other values are kept as is.
+// Returns *CycleError if the map references itself.
+//
+// It is safe to call concurrently with modifications; each nested map is converted from its own consistent snapshot.
+func (this *ConcurrentMap) ToRecursiveMap() (map[interface{}]interface{}, error) {
+	return toRecursiveMap(cycleGuard{}, nil, this)
+}
+
+// Converts the map and all nested *ConcurrentMap values into `map[string]interface{}`, e.g. to be passed to json.Marshal.
+//
+// It is the inverse of UnmarshalJSON. Non-string keys are converted with fmt.Sprint, so keys printed the same way overwrite each other.
+// Maps inside `[]interface{}` are converted as well and `[]*ConcurrentMap` becomes `[]map[string]interface{}`.
+// Any other values are kept as is. Returns *CycleError if the map references itself.
+//
+// It is safe to call concurrently with modifications; each nested map is converted from its own consistent snapshot.
+func (this *ConcurrentMap) ToRecursiveStringMap() (map[string]interface{}, error) {
+	return toRecursiveStringMap(cycleGuard{}, nil, this)
+}
+
+func toRecursiveMap(guard cycleGuard, path []interface{}, cm *ConcurrentMap) (map[interface{}]interface{}, error) {
+	key, err := guard.enterMap(path, cm)
+	if err != nil {
+		return nil, err
+	}
+	defer guard.leave(key)
+
+	items := snapshotItems(cm)
+	for k, value := range items {
+		if items[k], err = toRecursiveValue(guard, appendPath(path, k), value); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func toRecursiveValue(guard cycleGuard, path []interface{}, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case *ConcurrentMap:
+		return toRecursiveMap(guard, path, x)
+	case []interface{}:
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		sl := make([]interface{}, len(x))
+		for i, j := range x {
+			if sl[i], err = toRecursiveValue(guard, appendPath(path, i), j); err != nil {
+				return nil, err
+			}
+		}
+		return sl, nil
+	case []*ConcurrentMap:
+		sl := make([]map[interface{}]interface{}, len(x))
+		for i, j := range x {
+			m, err := toRecursiveMap(guard, appendPath(path, i), j)
+			if err != nil {
+				return nil, err
+			}
+			sl[i] = m
+		}
+		return sl, nil
+	}
+	return v, nil
+}
+
+func toRecursiveStringMap(guard cycleGuard, path []interface{}, cm *ConcurrentMap) (map[string]interface{}, error) {
+	key, err := guard.enterMap(path, cm)
+	if err != nil {
+		return nil, err
+	}
+	defer guard.leave(key)
+
+	items := snapshotItems(cm)
+	m := make(map[string]interface{}, len(items))
+	for k, value := range items {
+		if m[stringKey(k)], err = toRecursiveStringValue(guard, appendPath(path, k), value); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func toRecursiveStringValue(guard cycleGuard, path []interface{}, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case *ConcurrentMap:
+		return toRecursiveStringMap(guard, path, x)
+	case []interface{}:
+		key, err := guard.enter(path, reflect.ValueOf(x))
+		if err != nil {
+			return nil, err
+		}
+		defer guard.leave(key)
+
+		sl := make([]interface{}, len(x))
+		for i, j := range x {
+			if sl[i], err = toRecursiveStringValue(guard, appendPath(path, i), j); err != nil {
+				return nil, err
+			}
+		}
+		return sl, nil
+	case []*ConcurrentMap:
+		sl := make([]map[string]interface{}, len(x))
+		for i, j := range x {
+			m, err := toRecursiveStringMap(guard, appendPath(path, i), j)
+			if err != nil {
+				return nil, err
+			}
+			sl[i] = m
+		}
+		return sl, nil
+	}
+	return v, nil
+}
+
+func stringKey(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,126 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestToRecursiveMap(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Cm            *ConcurrentMap
+		ExpectedItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "new(ConcurrentMap)",
+			Cm:            new(ConcurrentMap),
+			ExpectedItems: map[interface{}]interface{}{},
+		},
+		{
+			TestAlias:     "Inverse of MakeRecursivelyConcurrentCopy",
+			Cm:            MakeRecursivelyConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", 2: 123, "Map": map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{3: 4.56}}}),
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", 2: 123, "Map": map[interface{}]interface{}{"key1": "stringValue", "Map": map[interface{}]interface{}{3: 4.56}}},
+		},
+		{
+			TestAlias: "Maps inside slices",
+			Cm: MakeConcurrentCopy(map[interface{}]interface{}{
+				"Slice":      []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}), []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})}},
+				"TypedSlice": []*ConcurrentMap{MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"})},
+				"PlainSlice": []int{1, 2, 3},
+			}),
+			ExpectedItems: map[interface{}]interface{}{
+				"Slice":      []interface{}{map[interface{}]interface{}{"key": "value"}, []interface{}{map[interface{}]interface{}{"key": "value"}}},
+				"TypedSlice": []map[interface{}]interface{}{{"key": "value"}},
+				"PlainSlice": []int{1, 2, 3},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Cm
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			actualItems, actualError := cm.ToRecursiveMap()
+
+			if actualError != nil {
+				t.Errorf("%s :: cm.ToRecursiveMap() returned unexpected error %v ", testAlias, actualError)
+			}
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.ToRecursiveMap() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestUnmarshalJSONToRecursiveStringMapCycle(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias string
+		JsonData  []byte
+	}{
+		{
+			TestAlias: "Simple key-value",
+			JsonData:  []byte(`{"key": "value"}`),
+		},
+		{
+			TestAlias: "Nested key-value",
+			JsonData:  []byte(`{"key": {"key": "value"}}`),
+		},
+		{
+			TestAlias: "Complex nested slice key-value",
+			JsonData:  []byte(`{"key": [{"key1": "value"}, [{"key2": "value"}, {"key2": 1.5}, {"key2": null}], {"key3": true}]}`),
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		jsonData := testCase.JsonData
+
+		testFn := func(t *testing.T) {
+
+			var expectedItems map[string]interface{}
+			if err := json.Unmarshal(jsonData, &expectedItems); err != nil {
+				t.Fatalf("%s :: json.Unmarshal(%s) returned unexpected error %v ", testAlias, jsonData, err)
+			}
+
+			cm := New(0)
+			if err := cm.UnmarshalJSON(jsonData); err != nil {
+				t.Fatalf("%s :: cm.UnmarshalJSON(%s) returned unexpected error %v ", testAlias, jsonData, err)
+			}
+
+			actualItems, actualError := cm.ToRecursiveStringMap()
+
+			if actualError != nil {
+				t.Errorf("%s :: cm.ToRecursiveStringMap() returned unexpected error %v ", testAlias, actualError)
+			}
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.UnmarshalJSON(%s); cm.ToRecursiveStringMap() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, jsonData, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
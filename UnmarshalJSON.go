@@ -14,7 +14,12 @@
 
 package concurrentmap
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
 
 // Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler).
 //
@@ -22,11 +27,17 @@ import "encoding/json"
 // Also, if some value represents a slice, it inspects its elements and unmarshals them into *ConcurrentMap if possible.
 //
 // While unmarshalling on non-empty map, overlapping key-values are overwritten.
+// If the map was created WithInsertionOrder, keys are inserted in the order of the document and nested objects are
+// unmarshalled into maps with the same option.
 //
 // It is safe to concurrently Unmarshal, Get and/or Set. However, JSON key-value(s) are guaranteed to be available as up to date only by completion of UnmarshalJSON() method.
 //
 // TODO(gopot) : improve performance and minimize allocs.
 func (this *ConcurrentMap) UnmarshalJSON(data []byte) error {
+	if this.order != nil {
+		return this.unmarshalOrderedJSON(data)
+	}
+
 	var m map[string]interface{}
 
 	err := json.Unmarshal(data, &m)
@@ -37,7 +48,9 @@ func (this *ConcurrentMap) UnmarshalJSON(data []byte) error {
 	cm := convertIntoConcurrentMapRecursively(m)
 
 	for key, value := range cm.Items() {
-		this.Set(key, value)
+		if err := this.TrySet(key, value); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -77,3 +90,54 @@ func convertIntoConcurrentMapRecursively(m map[string]interface{}) *ConcurrentMa
 	}
 	return cm
 }
+
+func (this *ConcurrentMap) unmarshalOrderedJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		// By convention of json.Unmarshaler, null is a no-op
+		return nil
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("concurrentmap: cannot unmarshal %v into ConcurrentMap", token)
+	}
+	entries, err := decodeOrderedEntries(decoder, newInsertionOrderedObject)
+	if err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("concurrentmap: unexpected data after top-level object")
+	}
+
+	for _, entry := range entries {
+		if err := this.TrySet(entry.Key, entry.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newInsertionOrderedObject(entries []Entry) interface{} {
+	cm := NewWithOptions(len(entries), WithInsertionOrder())
+	for _, entry := range entries {
+		cm.Set(entry.Key, entry.Value)
+	}
+	return cm
+}
+
+// Decodes members of a JSON object the same way UnmarshalJSON does, nested objects becoming *ConcurrentMap,
+// for other map types of the package. Returns no entries for `null`.
+func decodeJSONEntries(data []byte) ([]Entry, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(m))
+	for key, value := range m {
+		entries = append(entries, Entry{Key: key, Value: inspectAndConvertValueRecursively(value)})
+	}
+	return entries, nil
+}
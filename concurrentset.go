@@ -0,0 +1,169 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "encoding/json"
+
+// The ConcurrentSet type represents concurrent-safe set of comparable elements, built on ConcurrentMap.
+//
+// Set algebra works on consistent snapshots of each operand taken one after another, so operands may be modified concurrently.
+// The zero value is an empty set ready to use.
+type ConcurrentSet struct {
+	cm ConcurrentMap
+}
+
+// Instantiates and initializes ConcurrentSet holding `elements`.
+func NewSet(elements ...interface{}) *ConcurrentSet {
+	set := &ConcurrentSet{}
+	set.Add(elements...)
+	return set
+}
+
+// Returns a new set of the given snapshot.
+func newSetOf(items map[interface{}]interface{}) *ConcurrentSet {
+	set := &ConcurrentSet{}
+	set.cm.items = items
+	return set
+}
+
+// Adds elements to the set under a single lock acquisition.
+// Panics if any element cannot be used as a map key.
+func (this *ConcurrentSet) Add(elements ...interface{}) {
+	entries := make(map[interface{}]interface{}, len(elements))
+	for _, element := range elements {
+		entries[element] = struct{}{}
+	}
+	this.cm.SetMany(entries)
+}
+
+// Adds the element and returns true, if the set didn't contain it upon invokation.
+func (this *ConcurrentSet) AddIfAbsent(element interface{}) bool {
+	return this.cm.SetIfNotExists(element, struct{}{})
+}
+
+// Returns true if the set contains the element.
+func (this *ConcurrentSet) Contains(element interface{}) bool {
+	_, ok := this.cm.Get(element)
+	return ok
+}
+
+// Removes elements from the set under a single lock acquisition.
+func (this *ConcurrentSet) Remove(elements ...interface{}) {
+	this.cm.RemoveMany(elements)
+}
+
+// Returns number of elements in the set.
+func (this *ConcurrentSet) Len() int {
+	return this.cm.Len()
+}
+
+// Returns copy of elements of the set, in no particular order.
+func (this *ConcurrentSet) Items() []interface{} {
+	this.cm.readLock()
+	defer this.cm.lock.RUnlock()
+
+	x := make([]interface{}, 0, len(this.cm.items))
+	for element := range this.cm.items {
+		x = append(x, element)
+	}
+	return x
+}
+
+// Calls `fn` for every element of a snapshot of the set, until `fn` returns false.
+func (this *ConcurrentSet) Range(fn func(element interface{}) bool) {
+	for _, element := range this.Items() {
+		if !fn(element) {
+			return
+		}
+	}
+}
+
+// Returns a new set of elements contained in either set.
+func (this *ConcurrentSet) Union(other *ConcurrentSet) *ConcurrentSet {
+	items := this.cm.Items()
+	for element := range other.cm.Items() {
+		items[element] = struct{}{}
+	}
+	return newSetOf(items)
+}
+
+// Returns a new set of elements contained in both sets.
+func (this *ConcurrentSet) Intersect(other *ConcurrentSet) *ConcurrentSet {
+	items, others := this.cm.Items(), other.cm.Items()
+	for element := range items {
+		if _, ok := others[element]; !ok {
+			delete(items, element)
+		}
+	}
+	return newSetOf(items)
+}
+
+// Returns a new set of elements contained in this set but not in the `other`.
+func (this *ConcurrentSet) Difference(other *ConcurrentSet) *ConcurrentSet {
+	items := this.cm.Items()
+	for element := range other.cm.Items() {
+		delete(items, element)
+	}
+	return newSetOf(items)
+}
+
+// Returns a new set of elements contained in exactly one of the sets.
+func (this *ConcurrentSet) SymmetricDifference(other *ConcurrentSet) *ConcurrentSet {
+	items := this.cm.Items()
+	for element := range other.cm.Items() {
+		if _, ok := items[element]; ok {
+			delete(items, element)
+		} else {
+			items[element] = struct{}{}
+		}
+	}
+	return newSetOf(items)
+}
+
+// Returns true if every element of this set is contained in the `other`.
+func (this *ConcurrentSet) IsSubset(other *ConcurrentSet) bool {
+	items, others := this.cm.Items(), other.cm.Items()
+	if len(items) > len(others) {
+		return false
+	}
+	for element := range items {
+		if _, ok := others[element]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), rendering the set as JSON array.
+// Elements are rendered in a stable order, so equal sets render the same.
+func (this *ConcurrentSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sortedKeys(this.cm.Items()))
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), adding elements of JSON array to the set.
+// Returns ErrUnhashableKey if any element is an array or an object, before any element is added.
+func (this *ConcurrentSet) UnmarshalJSON(data []byte) error {
+	var elements []interface{}
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return err
+	}
+	for _, element := range elements {
+		if err := checkHashable(element); err != nil {
+			return err
+		}
+	}
+	this.Add(elements...)
+	return nil
+}
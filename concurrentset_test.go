@@ -0,0 +1,151 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestConcurrentSetAlgebra(t *testing.T) {
+
+	a := NewSet(1, 2, 3, 4)
+	b := NewSet(3, 4, 5)
+
+	testCases := []struct {
+		TestAlias        string
+		Result           *ConcurrentSet
+		ExpectedElements []interface{}
+	}{
+		{TestAlias: "Union", Result: a.Union(b), ExpectedElements: []interface{}{1, 2, 3, 4, 5}},
+		{TestAlias: "Intersect", Result: a.Intersect(b), ExpectedElements: []interface{}{3, 4}},
+		{TestAlias: "Difference", Result: a.Difference(b), ExpectedElements: []interface{}{1, 2}},
+		{TestAlias: "SymmetricDifference", Result: a.SymmetricDifference(b), ExpectedElements: []interface{}{1, 2, 5}},
+		{TestAlias: "Union with empty set", Result: a.Union(&ConcurrentSet{}), ExpectedElements: []interface{}{1, 2, 3, 4}},
+		{TestAlias: "Intersect with empty set", Result: a.Intersect(NewSet()), ExpectedElements: []interface{}{}},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		result := testCase.Result
+		expectedElements := testCase.ExpectedElements
+
+		testFn := func(t *testing.T) {
+
+			actualElements := result.Items()
+			sort.Slice(actualElements, func(i, j int) bool { return actualElements[i].(int) < actualElements[j].(int) })
+
+			if !reflect.DeepEqual(actualElements, expectedElements) {
+				t.Errorf("%s :: result.Items() returned %v while expected %v ", testAlias, actualElements, expectedElements)
+			}
+			if actualLen := result.Len(); actualLen != len(expectedElements) {
+				t.Errorf("%s :: result.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedElements))
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+	// Results are independent of operands
+	a.Union(b).Add(100)
+	if a.Contains(100) || b.Contains(100) {
+		t.Errorf("modification of a.Union(b) is visible through operands ")
+	}
+
+}
+
+func TestConcurrentSetOperations(t *testing.T) {
+
+	var set ConcurrentSet
+
+	set.Add("a", "b")
+	if !set.AddIfAbsent("c") {
+		t.Errorf("set.AddIfAbsent(\"c\") returned false while expected true ")
+	}
+	if set.AddIfAbsent("a") {
+		t.Errorf("set.AddIfAbsent(\"a\") returned true while expected false ")
+	}
+	set.Remove("b", "missing")
+
+	if !set.Contains("a") || set.Contains("b") || !set.Contains("c") {
+		t.Errorf("set holds %v while expected [a c] ", set.Items())
+	}
+	if !set.IsSubset(NewSet("a", "c", "d")) {
+		t.Errorf("set.IsSubset([a c d]) returned false while expected true ")
+	}
+	if set.IsSubset(NewSet("a", "d")) {
+		t.Errorf("set.IsSubset([a d]) returned true while expected false ")
+	}
+
+	visited := 0
+	set.Range(func(element interface{}) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("set.Range(...) visited %d elements after fn returned false ", visited)
+	}
+
+}
+
+func TestConcurrentSetJSON(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		JSON          string
+		ExpectedJSON  string
+		ExpectedError error
+	}{
+		{TestAlias: "Empty array", JSON: `[]`, ExpectedJSON: `[]`},
+		{TestAlias: "Duplicates are merged", JSON: `["b", "a", "b", 1, true, null]`, ExpectedJSON: `[1,null,"a","b",true]`},
+		{TestAlias: "Unhashable element", JSON: `["a", {"b": 1}]`, ExpectedError: ErrUnhashableKey},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		data := testCase.JSON
+		expectedJSON := testCase.ExpectedJSON
+		expectedError := testCase.ExpectedError
+
+		testFn := func(t *testing.T) {
+
+			set := NewSet()
+			err := json.Unmarshal([]byte(data), set)
+
+			if !errors.Is(err, expectedError) || (err == nil) != (expectedError == nil) {
+				t.Fatalf("%s :: json.Unmarshal(...) returned error %v while expected %v ", testAlias, err, expectedError)
+			}
+			if expectedError != nil {
+				if set.Len() != 0 {
+					t.Errorf("%s :: failed json.Unmarshal(...) added %v ", testAlias, set.Items())
+				}
+				return
+			}
+			actualJSON, err := json.Marshal(set)
+			if err != nil {
+				t.Errorf("%s :: json.Marshal(set) returned unexpected error %v ", testAlias, err)
+			}
+			if string(actualJSON) != expectedJSON {
+				t.Errorf("%s :: json.Marshal(set) returned %s while expected %s ", testAlias, actualJSON, expectedJSON)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,221 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default values
+const (
+	// Represents default number of stripes of every counter of CounterMap
+	DEFAULT_COUNTERSTRIPES = 8
+)
+
+// The CounterMap type represents concurrent-safe map of int64 counters, e.g. per-endpoint request counts.
+//
+// Every counter is split into stripes, each on its own cache line, and every increment goes to a random stripe,
+// so goroutines incrementing the same hot key rarely touch the same cache line. Reads sum the stripes.
+// Lookups of existing keys never lock.
+// The zero value is an empty map with DEFAULT_COUNTERSTRIPES stripes per counter ready to use.
+type CounterMap struct {
+	stripes  int
+	counters sync.Map
+}
+
+// Represents a single counter, as returned by TopN.
+type CounterEntry struct {
+	Key   interface{}
+	Count int64
+}
+
+// Represents a counter of CounterMap.
+type stripedCounter []paddedInt64
+
+// Represents int64 occupying a whole cache line, so that neighbours are not invalidated by its updates.
+type paddedInt64 struct {
+	atomic.Int64
+	_ [56]byte
+}
+
+// Instantiates empty CounterMap with `stripes` stripes per counter, rounded up to a power of two.
+// Non-positive `stripes` means DEFAULT_COUNTERSTRIPES; use 1 for keys which are rarely updated concurrently.
+func NewCounterMap(stripes int) *CounterMap {
+	if stripes <= 0 {
+		stripes = DEFAULT_COUNTERSTRIPES
+	}
+	return &CounterMap{stripes: 1 << bits.Len(uint(stripes-1))}
+}
+
+// Returns the counter of the key, creating it if needed.
+func (this *CounterMap) counter(key interface{}) stripedCounter {
+	if counter, ok := this.counters.Load(key); ok {
+		return counter.(stripedCounter)
+	}
+	stripes := this.stripes
+	if stripes == 0 {
+		// The zero value
+		stripes = DEFAULT_COUNTERSTRIPES
+	}
+	counter, _ := this.counters.LoadOrStore(key, make(stripedCounter, stripes))
+	return counter.(stripedCounter)
+}
+
+// Increments the counter of the key by one, creating it with zero if needed.
+func (this *CounterMap) Inc(key interface{}) {
+	this.Add(key, 1)
+}
+
+// Adds `delta` to the counter of the key, creating it with zero if needed.
+func (this *CounterMap) Add(key interface{}, delta int64) {
+	counter := this.counter(key)
+	counter[rand.Uint32()&uint32(len(counter)-1)].Add(delta)
+}
+
+// Returns the value of the counter of the key, or zero if there is no such counter.
+func (this *CounterMap) Get(key interface{}) int64 {
+	counter, ok := this.counters.Load(key)
+	if !ok {
+		return 0
+	}
+	return counter.(stripedCounter).sum()
+}
+
+// Sets the counter of the key to zero and returns its previous value.
+// Increments concurrent with the reset are either included in the returned value or kept by the counter, never lost.
+func (this *CounterMap) Reset(key interface{}) int64 {
+	counter, ok := this.counters.Load(key)
+	if !ok {
+		return 0
+	}
+	stripes := counter.(stripedCounter)
+	sum := int64(0)
+	for i := range stripes {
+		sum += stripes[i].Swap(0)
+	}
+	return sum
+}
+
+// Removes the counter of the key and returns its value.
+// Unlike Reset, increments racing with the removal may be lost.
+func (this *CounterMap) Remove(key interface{}) int64 {
+	counter, ok := this.counters.LoadAndDelete(key)
+	if !ok {
+		return 0
+	}
+	return counter.(stripedCounter).sum()
+}
+
+// Returns number of counters.
+func (this *CounterMap) Len() int {
+	n := 0
+	this.counters.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Returns values of all counters. Counters are read one by one, so it is not a consistent snapshot of concurrently updated counters.
+func (this *CounterMap) Snapshot() map[interface{}]int64 {
+	x := map[interface{}]int64{}
+	this.counters.Range(func(key, value interface{}) bool {
+		x[key] = value.(stripedCounter).sum()
+		return true
+	})
+	return x
+}
+
+// Returns up to `n` counters with the highest values in descending order; counters with equal values are ordered by their printed keys.
+func (this *CounterMap) TopN(n int) []CounterEntry {
+	snapshot := this.Snapshot()
+	entries := make([]CounterEntry, 0, len(snapshot))
+	for key, count := range snapshot {
+		entries = append(entries, CounterEntry{Key: key, Count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return fmt.Sprint(entries[i].Key) < fmt.Sprint(entries[j].Key)
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// Multiplies every counter by `factor`, truncating toward zero, e.g. 0.5 halves counters to turn them into rate-style metrics.
+// Every stripe is scaled atomically, so concurrent increments are not lost, though they may be scaled as well.
+func (this *CounterMap) Decay(factor float64) {
+	this.counters.Range(func(key, value interface{}) bool {
+		stripes := value.(stripedCounter)
+		for i := range stripes {
+			stripe := &stripes[i]
+			for {
+				old := stripe.Load()
+				if stripe.CompareAndSwap(old, int64(float64(old)*factor)) {
+					break
+				}
+			}
+		}
+		return true
+	})
+}
+
+// Starts decaying counters by `factor` every `interval` in background, see Decay.
+// Returns a function stopping it, which waits for a decay in progress to complete and may be called more than once.
+func (this *CounterMap) StartDecay(interval time.Duration, factor float64) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				this.Decay(factor)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+		<-stopped
+	}
+}
+
+func (this stripedCounter) sum() int64 {
+	sum := int64(0)
+	for i := range this {
+		sum += this[i].Load()
+	}
+	return sum
+}
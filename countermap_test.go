@@ -0,0 +1,196 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestCounterMap(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias        string
+		Stripes          int
+		ModifyFn         func(cm *CounterMap)
+		ExpectedSnapshot map[interface{}]int64
+		ExpectedTop2     []CounterEntry
+	}{
+		{
+			TestAlias:        "Empty map",
+			Stripes:          0,
+			ModifyFn:         func(cm *CounterMap) {},
+			ExpectedSnapshot: map[interface{}]int64{},
+			ExpectedTop2:     []CounterEntry{},
+		},
+		{
+			TestAlias: "Inc and Add",
+			Stripes:   3,
+			ModifyFn: func(cm *CounterMap) {
+				cm.Inc("/a")
+				cm.Inc("/a")
+				cm.Add("/b", 10)
+				cm.Add("/c", -1)
+				cm.Inc("/c")
+			},
+			ExpectedSnapshot: map[interface{}]int64{"/a": 2, "/b": 10, "/c": 0},
+			ExpectedTop2:     []CounterEntry{{Key: "/b", Count: 10}, {Key: "/a", Count: 2}},
+		},
+		{
+			TestAlias: "Reset and Remove",
+			Stripes:   1,
+			ModifyFn: func(cm *CounterMap) {
+				cm.Add("/a", 5)
+				cm.Add("/b", 7)
+				cm.Add("/c", 7)
+				cm.Reset("/a")
+				cm.Remove("/d")
+				cm.Remove("/c")
+			},
+			ExpectedSnapshot: map[interface{}]int64{"/a": 0, "/b": 7},
+			ExpectedTop2:     []CounterEntry{{Key: "/b", Count: 7}, {Key: "/a", Count: 0}},
+		},
+		{
+			TestAlias: "Ties are ordered by key",
+			Stripes:   8,
+			ModifyFn: func(cm *CounterMap) {
+				cm.Add("/c", 3)
+				cm.Add("/a", 3)
+				cm.Add("/b", 3)
+			},
+			ExpectedSnapshot: map[interface{}]int64{"/a": 3, "/b": 3, "/c": 3},
+			ExpectedTop2:     []CounterEntry{{Key: "/a", Count: 3}, {Key: "/b", Count: 3}},
+		},
+		{
+			TestAlias: "Decay",
+			Stripes:   1,
+			ModifyFn: func(cm *CounterMap) {
+				cm.Add("/a", 10)
+				cm.Add("/b", 3)
+				cm.Decay(0.5)
+			},
+			ExpectedSnapshot: map[interface{}]int64{"/a": 5, "/b": 1},
+			ExpectedTop2:     []CounterEntry{{Key: "/a", Count: 5}, {Key: "/b", Count: 1}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		stripes := testCase.Stripes
+		modifyFn := testCase.ModifyFn
+		expectedSnapshot := testCase.ExpectedSnapshot
+		expectedTop2 := testCase.ExpectedTop2
+
+		testFn := func(t *testing.T) {
+
+			cm := NewCounterMap(stripes)
+
+			modifyFn(cm)
+
+			if actualSnapshot := cm.Snapshot(); !reflect.DeepEqual(actualSnapshot, expectedSnapshot) {
+				t.Errorf("%s :: cm.Snapshot() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualSnapshot, expectedSnapshot)
+			}
+			if actualLen := cm.Len(); actualLen != len(expectedSnapshot) {
+				t.Errorf("%s :: cm.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedSnapshot))
+			}
+			for key, expectedCount := range expectedSnapshot {
+				if actualCount := cm.Get(key); actualCount != expectedCount {
+					t.Errorf("%s :: cm.Get(%#v) returned %d while expected %d ", testAlias, key, actualCount, expectedCount)
+				}
+			}
+			if actualTop2 := cm.TopN(2); !reflect.DeepEqual(actualTop2, expectedTop2) {
+				t.Errorf("%s :: cm.TopN(2) returned %#v while expected %#v ", testAlias, actualTop2, expectedTop2)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestCounterMapConcurrentIncrements(t *testing.T) {
+
+	cm := NewCounterMap(0)
+
+	reset := int64(0)
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				cm.Inc("hot")
+				cm.Add(g, 2)
+			}
+		}(g)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			reset += cm.Reset("hot")
+		}
+	}()
+	wg.Wait()
+
+	if actualCount := cm.Get("hot") + reset; actualCount != 8000 {
+		t.Errorf("cm.Get(\"hot\") plus reset values is %d while expected 8000 ", actualCount)
+	}
+	for g := 0; g < 8; g++ {
+		if actualCount := cm.Get(g); actualCount != 2000 {
+			t.Errorf("cm.Get(%d) returned %d while expected 2000 ", g, actualCount)
+		}
+	}
+
+}
+
+func TestCounterMapZeroValue(t *testing.T) {
+
+	var cm CounterMap
+
+	cm.Inc("hot")
+	cm.Add("hot", 2)
+	if actualCount := cm.Get("hot"); actualCount != 3 {
+		t.Errorf("cm.Get(\"hot\") returned %d for zero value while expected 3 ", actualCount)
+	}
+
+}
+
+func TestCounterMapStartDecay(t *testing.T) {
+
+	cm := NewCounterMap(1)
+	cm.Add("/a", 1<<20)
+
+	stop := cm.StartDecay(time.Millisecond, 0.5)
+	deadline := time.Now().Add(5 * time.Second)
+	for cm.Get("/a") == 1<<20 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	stop()
+
+	decayed := cm.Get("/a")
+	if decayed >= 1<<20 {
+		t.Fatalf("cm.Get(\"/a\") returned %d, counter has not decayed ", decayed)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if actual := cm.Get("/a"); actual != decayed {
+		t.Errorf("cm.Get(\"/a\") returned %d after stop while expected %d ", actual, decayed)
+	}
+
+}
@@ -0,0 +1,252 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// The CopyOnWriteMap type represents concurrent-safe map optimized for read-mostly workloads.
+//
+// Reads are a single atomic load of an immutable map and never block or contend with each other or with writers.
+// Every write clones the whole map and atomically publishes the clone, so writes cost O(n) and are serialized.
+// Use batch methods to apply many changes with a single clone.
+//
+// UnmarshalJSON publishes the whole decoded document with a single clone as well, and MarshalJSON renders one snapshot.
+// Unhashable keys panic the way they do in Go maps, TryGet, TrySet, TrySetIfNotExists and TryRemove return ErrUnhashableKey instead.
+// The zero value is an empty map ready to use.
+type CopyOnWriteMap struct {
+	items atomic.Pointer[map[interface{}]interface{}]
+	// Serializes writers, readers never take it
+	lock sync.Mutex
+}
+
+// Instantiates and initializes CopyOnWriteMap with the copy of `m`.
+func NewCopyOnWrite(m map[interface{}]interface{}) *CopyOnWriteMap {
+	cm := &CopyOnWriteMap{}
+	items := make(map[interface{}]interface{}, len(m))
+	for key, value := range m {
+		items[key] = value
+	}
+	cm.items.Store(&items)
+	return cm
+}
+
+// Returns currently published map, which must not be modified.
+func (this *CopyOnWriteMap) load() map[interface{}]interface{} {
+	if items := this.items.Load(); items != nil {
+		return *items
+	}
+	return nil
+}
+
+// Clones currently published map, lets `fn` modify the clone and publishes it.
+// Nothing is published if `fn` returns false.
+func (this *CopyOnWriteMap) update(extraCap int, fn func(items map[interface{}]interface{}) bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	current := this.load()
+	items := make(map[interface{}]interface{}, len(current)+extraCap)
+	for key, value := range current {
+		items[key] = value
+	}
+	if fn(items) {
+		this.items.Store(&items)
+	}
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *CopyOnWriteMap) Get(key interface{}) (interface{}, bool) {
+	val, ok := this.load()[key]
+	return val, ok
+}
+
+// Sets the given value under the specified key.
+func (this *CopyOnWriteMap) Set(key interface{}, val interface{}) {
+	this.update(DEFAULT_ONSETCAPACITY, func(items map[interface{}]interface{}) bool {
+		items[key] = val
+		return true
+	})
+}
+
+// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with the same key.
+func (this *CopyOnWriteMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	if _, ok := this.Get(key); ok {
+		// Avoid cloning when the key obviously exists
+		return false
+	}
+	inserted := false
+	this.update(DEFAULT_ONSETCAPACITY, func(items map[interface{}]interface{}) bool {
+		if _, ok := items[key]; !ok {
+			items[key] = val
+			inserted = true
+		}
+		return inserted
+	})
+	return inserted
+}
+
+// Removes an element from the map.
+func (this *CopyOnWriteMap) Remove(key interface{}) {
+	if _, ok := this.Get(key); !ok {
+		// Avoid cloning when there is nothing to remove
+		return
+	}
+	this.update(0, func(items map[interface{}]interface{}) bool {
+		delete(items, key)
+		return true
+	})
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+func (this *CopyOnWriteMap) Items() map[interface{}]interface{} {
+	current := this.load()
+	x := make(map[interface{}]interface{}, len(current))
+	for key, value := range current {
+		x[key] = value
+	}
+	return x
+}
+
+// Returns number of elements in the map.
+func (this *CopyOnWriteMap) Len() int {
+	return len(this.load())
+}
+
+// Sets all the given entries with a single clone of the map.
+func (this *CopyOnWriteMap) SetMany(entries map[interface{}]interface{}) {
+	this.update(len(entries), func(items map[interface{}]interface{}) bool {
+		for key, val := range entries {
+			items[key] = val
+		}
+		return true
+	})
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, with a single clone of the map.
+// Returns keys of inserted entries; entries with already existing keys are left untouched.
+func (this *CopyOnWriteMap) SetManyIfNotExists(entries map[interface{}]interface{}) []interface{} {
+	inserted := make([]interface{}, 0, len(entries))
+	this.update(len(entries), func(items map[interface{}]interface{}) bool {
+		for key, val := range entries {
+			if _, ok := items[key]; !ok {
+				items[key] = val
+				inserted = append(inserted, key)
+			}
+		}
+		return len(inserted) > 0
+	})
+	return inserted
+}
+
+// Retrieves elements under given keys from a single snapshot of the map.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *CopyOnWriteMap) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	current := this.load()
+	found := make(map[interface{}]interface{}, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if val, ok := current[key]; ok {
+			found[key] = val
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys with a single clone of the map.
+func (this *CopyOnWriteMap) RemoveMany(keys []interface{}) {
+	this.update(0, func(items map[interface{}]interface{}) bool {
+		for _, key := range keys {
+			delete(items, key)
+		}
+		return true
+	})
+}
+
+// Retrieves an element from map under given key, same as Get.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *CopyOnWriteMap) TryGet(key interface{}) (interface{}, bool, error) {
+	if err := checkHashable(key); err != nil {
+		return nil, false, err
+	}
+	val, ok := this.Get(key)
+	return val, ok, nil
+}
+
+// Sets the given value under the specified key, same as Set.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *CopyOnWriteMap) TrySet(key interface{}, val interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Set(key, val)
+	return nil
+}
+
+// Sets the given value under the specified key if the key didn't exist upon invokation, same as SetIfNotExists.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *CopyOnWriteMap) TrySetIfNotExists(key interface{}, val interface{}) (bool, error) {
+	if err := checkHashable(key); err != nil {
+		return false, err
+	}
+	return this.SetIfNotExists(key, val), nil
+}
+
+// Removes an element from the map, same as Remove.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *CopyOnWriteMap) TryRemove(key interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Remove(key)
+	return nil
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), see ConcurrentMap.MarshalJSON.
+// The map is rendered from a single snapshot, so it never blocks writers.
+func (this *CopyOnWriteMap) MarshalJSON() ([]byte, error) {
+	return marshalJSONEntries(this)
+}
+
+func (this *CopyOnWriteMap) jsonEntries() []Entry {
+	current := this.load()
+	entries := make([]Entry, 0, len(current))
+	for key, value := range current {
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), see ConcurrentMap.UnmarshalJSON.
+// All decoded entries are published with a single clone of the map.
+func (this *CopyOnWriteMap) UnmarshalJSON(data []byte) error {
+	entries, err := decodeJSONEntries(data)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	items := make(map[interface{}]interface{}, len(entries))
+	for _, entry := range entries {
+		items[entry.Key] = entry.Value
+	}
+	this.SetMany(items)
+	return nil
+}
@@ -0,0 +1,197 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestCopyOnWriteMapItemsCycle(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Cm            *CopyOnWriteMap
+		ModifyFn      func(cm *CopyOnWriteMap)
+		ExpectedItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "new(CopyOnWriteMap) and Set non-existing key",
+			Cm:            new(CopyOnWriteMap),
+			ModifyFn:      func(cm *CopyOnWriteMap) { cm.Set("key3", 4.56) },
+			ExpectedItems: map[interface{}]interface{}{"key3": 4.56},
+		},
+		{
+			TestAlias:     "NewCopyOnWrite and Set existing key",
+			Cm:            NewCopyOnWrite(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			ModifyFn:      func(cm *CopyOnWriteMap) { cm.Set("key2", 321) },
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 321},
+		},
+		{
+			TestAlias:     "NewCopyOnWrite and SetIfNotExists existing key",
+			Cm:            NewCopyOnWrite(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			ModifyFn:      func(cm *CopyOnWriteMap) { cm.SetIfNotExists("key2", 321) },
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+		},
+		{
+			TestAlias:     "NewCopyOnWrite and Remove existing key",
+			Cm:            NewCopyOnWrite(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			ModifyFn:      func(cm *CopyOnWriteMap) { cm.Remove("key2") },
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+		},
+		{
+			TestAlias: "NewCopyOnWrite and batch methods",
+			Cm:        NewCopyOnWrite(map[interface{}]interface{}{"key1": "stringValue", "key2": 123}),
+			ModifyFn: func(cm *CopyOnWriteMap) {
+				cm.SetMany(map[interface{}]interface{}{"key3": 4.56, "key4": true})
+				cm.SetManyIfNotExists(map[interface{}]interface{}{"key1": "otherValue", "key5": nil})
+				cm.RemoveMany([]interface{}{"key2", "key4"})
+			},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key3": 4.56, "key5": nil},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Cm
+		modifyFn := testCase.ModifyFn
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			before := cm.Items()
+
+			modifyFn(cm)
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+			if cm.Len() != len(expectedItems) {
+				t.Errorf("%s :: cm.Len() returned %d while expected %d ", testAlias, cm.Len(), len(expectedItems))
+			}
+
+			keys := []interface{}{"missingKey"}
+			for key := range expectedItems {
+				keys = append(keys, key)
+			}
+			actualFound, actualMissing := cm.GetMany(keys)
+
+			if !(reflect.DeepEqual(actualFound, expectedItems)) || !(reflect.DeepEqual(actualMissing, []interface{}{"missingKey"})) {
+				t.Errorf("%s :: cm.GetMany(%#v) returned \r\n %#v, %#v \r\n while expected \r\n %#v, [missingKey] ", testAlias, keys, actualFound, actualMissing, expectedItems)
+			}
+
+			before["mutated"] = true
+			if _, ok := cm.Get("mutated"); ok {
+				t.Errorf("%s :: modifying result of cm.Items() affected the map ", testAlias)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestCopyOnWriteMapConcurrently(t *testing.T) {
+
+	cm := new(CopyOnWriteMap)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				cm.Set(g*1000+n, n)
+				cm.SetIfNotExists(g*1000+n, -1)
+			}
+		}(g)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 2000; n++ {
+				if val, ok := cm.Get(g*1000 + n%200); ok && val != n%200 {
+					t.Errorf("cm.Get(%d) returned %v while expected %d ", g*1000+n%200, val, n%200)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if cm.Len() != 800 {
+		t.Errorf("cm.Len() after concurrent modifications returned %d while expected 800 ", cm.Len())
+	}
+}
+
+func TestCopyOnWriteMapTryMethods(t *testing.T) {
+
+	cm := NewCopyOnWrite(nil)
+	unhashable := []interface{}{"slice"}
+
+	if err := cm.TrySet(unhashable, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TrySet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, _, err := cm.TryGet(unhashable); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TryGet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, err := cm.TrySetIfNotExists(unhashable, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TrySetIfNotExists(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if err := cm.TryRemove(unhashable); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TryRemove(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+
+	if err := cm.TrySet("key", 1); err != nil {
+		t.Errorf("cm.TrySet(key) returned unexpected error %v ", err)
+	}
+	if inserted, err := cm.TrySetIfNotExists("key", 2); inserted || err != nil {
+		t.Errorf("cm.TrySetIfNotExists(key) returned %v, %v while expected false, nil ", inserted, err)
+	}
+	if val, ok, err := cm.TryGet("key"); val != 1 || !ok || err != nil {
+		t.Errorf("cm.TryGet(key) returned %v, %v, %v while expected 1, true, nil ", val, ok, err)
+	}
+	if err := cm.TryRemove("key"); err != nil || cm.Len() != 0 {
+		t.Errorf("cm.TryRemove(key) returned %v and left %d elements ", err, cm.Len())
+	}
+}
+
+func TestCopyOnWriteMapJSON(t *testing.T) {
+
+	cm := NewCopyOnWrite(map[interface{}]interface{}{"flag": false})
+	if err := json.Unmarshal([]byte(`{"flag": true, "rollout": {"percent": 10}}`), cm); err != nil {
+		t.Fatalf("json.Unmarshal(...) returned unexpected error %v ", err)
+	}
+	if val, _ := cm.Get("flag"); val != true {
+		t.Errorf("cm.Get(flag) returned %v while expected decoded value true ", val)
+	}
+	if rollout, _ := cm.Get("rollout"); !reflect.DeepEqual(rollout, MakeConcurrentCopy(map[interface{}]interface{}{"percent": float64(10)})) {
+		t.Errorf("cm.Get(rollout) returned %#v while expected nested *ConcurrentMap ", rollout)
+	}
+	if err := json.Unmarshal([]byte(`null`), cm); err != nil || cm.Len() != 2 {
+		t.Errorf("json.Unmarshal(null) returned %v and left %d elements while expected a no-op ", err, cm.Len())
+	}
+
+	actualJSON, err := json.Marshal(cm)
+	if err != nil {
+		t.Fatalf("json.Marshal(cm) returned unexpected error %v ", err)
+	}
+	if expected := `{"flag":true,"rollout":{"percent":10}}`; string(actualJSON) != expected {
+		t.Errorf("json.Marshal(cm) returned %s while expected %s ", actualJSON, expected)
+	}
+}
@@ -0,0 +1,740 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Internal values of Ctrie
+const (
+	// Represents number of hash bits consumed per level of the trie
+	ctrieLevelBits = 5
+	// Represents mask of hash bits consumed per level of the trie
+	ctrieLevelMask = 1<<ctrieLevelBits - 1
+	// Represents number of hash bits, deeper than which colliding keys are kept in lists
+	ctrieHashBits = 64
+)
+
+// The Ctrie type represents lock-free concurrent hash trie, as described in
+// [Concurrent Tries with Efficient Non-Blocking Snapshots](https://aleksandar-prokopec.com/resources/docs/ctries-snapshot.pdf).
+//
+// Reads and writes never block each other: every operation is a sequence of atomic compare-and-swap steps
+// which other operations help to complete. Snapshot is O(1) and returns an independent, readable and writable map
+// sharing structure with the original, which is lazily copied by whichever of them modifies a shared part first.
+//
+// Items, Range, GetMany and MarshalJSON work on an O(1) read-only snapshot, so they see a consistent state and never block writers.
+// SetMany and other batch writes are not atomic: every entry is a separate lock-free operation, which readers may observe one by one.
+// Keys must be of comparable types; TryGet, TrySet and the other checked methods return ErrUnhashableKey for the rest.
+// The zero value is an empty map ready to use.
+type Ctrie struct {
+	root     atomic.Pointer[ctrieRoot]
+	readOnly bool
+}
+
+// Identifies a generation of trie nodes; every snapshot starts a new one.
+// It must not be of zero size, otherwise distinct generations may share the same address.
+type ctrieGeneration struct {
+	_ byte
+}
+
+// Holds either the root iNode or an RDCSS descriptor replacing the root.
+type ctrieRoot struct {
+	in   *iNode
+	desc *rdcssDescriptor
+}
+
+// Represents a double-compare single-swap of the root, see rdcss.
+type rdcssDescriptor struct {
+	old       *ctrieRoot
+	expected  *mainNode
+	new       *ctrieRoot
+	committed atomic.Bool
+}
+
+// Represents an indirection node, the only mutable node of the trie.
+type iNode struct {
+	main atomic.Pointer[mainNode]
+	gen  *ctrieGeneration
+}
+
+// Represents the content of an iNode: exactly one of cNode, tNode, lNode or failed is set.
+// Main nodes are immutable except prev, which links to the replaced main node until the replacement is committed, see gcas.
+type mainNode struct {
+	cNode  *cNode
+	tNode  *sNode
+	lNode  []*sNode
+	failed *mainNode
+	prev   atomic.Pointer[mainNode]
+}
+
+// Represents a branching node: a bitmap of present branches and the array of *iNode or *sNode branches.
+type cNode struct {
+	bmp   uint32
+	array []interface{}
+	gen   *ctrieGeneration
+}
+
+// Represents a key-value leaf.
+type sNode struct {
+	key   interface{}
+	hash  uint64
+	value interface{}
+}
+
+// Signals that an operation has to be restarted from the root.
+type ctrieRestart struct{}
+
+var restart = &ctrieRestart{}
+
+// Instantiates and initializes an empty Ctrie.
+func NewCtrie() *Ctrie {
+	ct := &Ctrie{}
+	ct.root.Store(newCtrieRoot(&ctrieGeneration{}))
+	return ct
+}
+
+func newCtrieRoot(gen *ctrieGeneration) *ctrieRoot {
+	in := &iNode{gen: gen}
+	in.main.Store(&mainNode{cNode: &cNode{gen: gen}})
+	return &ctrieRoot{in: in}
+}
+
+func ctrieHash(key interface{}) uint64 {
+	return maphash.Comparable(hashSeed, key)
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *Ctrie) Get(key interface{}) (interface{}, bool) {
+	hash := ctrieHash(key)
+	for {
+		root := this.readRoot(false)
+		value, ok, res := this.ilookup(root, key, hash, 0, nil, root.gen)
+		if res != restart {
+			return value, ok
+		}
+	}
+}
+
+// Sets the given value under the specified key.
+func (this *Ctrie) Set(key interface{}, val interface{}) {
+	this.insert(key, val, false)
+}
+
+// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with the same key.
+func (this *Ctrie) SetIfNotExists(key interface{}, val interface{}) bool {
+	return this.insert(key, val, true)
+}
+
+// Removes an element from the map.
+func (this *Ctrie) Remove(key interface{}) {
+	hash := ctrieHash(key)
+	for {
+		root := this.readRoot(false)
+		if this.iremove(root, key, hash, 0, nil, root.gen) != restart {
+			return
+		}
+	}
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+// The copy reflects a consistent state, taken by an O(1) snapshot, and does not block writers.
+func (this *Ctrie) Items() map[interface{}]interface{} {
+	x := make(map[interface{}]interface{})
+	this.Range(func(key, value interface{}) bool {
+		x[key] = value
+		return true
+	})
+	return x
+}
+
+// Returns number of elements in the map. It is O(n), as it counts elements of a read-only snapshot.
+func (this *Ctrie) Len() int {
+	n := 0
+	this.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Calls `fn` for every element of a consistent O(1) snapshot of the map, until `fn` returns false.
+// The map can be modified, including by `fn`, without affecting the iteration.
+func (this *Ctrie) Range(fn func(key, value interface{}) bool) {
+	snapshot := this.snapshot(true)
+	snapshot.traverse(snapshot.readRoot(false), fn)
+}
+
+// Returns an independent, readable and writable copy of the map in O(1).
+// Neither the map nor the copy observe modifications of each other made afterwards.
+func (this *Ctrie) Snapshot() *Ctrie {
+	return this.snapshot(false)
+}
+
+func (this *Ctrie) snapshot(readOnly bool) *Ctrie {
+	for {
+		root := this.readRoot(false)
+		main := this.gcasRead(root)
+		if this.rdcss(this.root.Load(), root, main, root.copyToGen(&ctrieGeneration{}, this)) {
+			ct := &Ctrie{readOnly: readOnly}
+			if readOnly {
+				ct.root.Store(&ctrieRoot{in: root})
+			} else {
+				ct.root.Store(&ctrieRoot{in: root.copyToGen(&ctrieGeneration{}, this)})
+			}
+			return ct
+		}
+	}
+}
+
+// Retrieves an element from map under given key, same as Get.
+// Returns ErrUnhashableKey instead of panicking if the key is not comparable.
+func (this *Ctrie) TryGet(key interface{}) (interface{}, bool, error) {
+	if err := checkHashable(key); err != nil {
+		return nil, false, err
+	}
+	val, ok := this.Get(key)
+	return val, ok, nil
+}
+
+// Sets the given value under the specified key, same as Set.
+// Returns ErrUnhashableKey instead of panicking if the key is not comparable.
+func (this *Ctrie) TrySet(key interface{}, val interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Set(key, val)
+	return nil
+}
+
+// Sets the given value under the specified key if the key didn't exist upon invokation, same as SetIfNotExists.
+// Returns ErrUnhashableKey instead of panicking if the key is not comparable.
+func (this *Ctrie) TrySetIfNotExists(key interface{}, val interface{}) (bool, error) {
+	if err := checkHashable(key); err != nil {
+		return false, err
+	}
+	return this.SetIfNotExists(key, val), nil
+}
+
+// Removes an element from the map, same as Remove.
+// Returns ErrUnhashableKey instead of panicking if the key is not comparable.
+func (this *Ctrie) TryRemove(key interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Remove(key)
+	return nil
+}
+
+// Sets all the given entries, one by one.
+func (this *Ctrie) SetMany(entries map[interface{}]interface{}) {
+	for key, val := range entries {
+		this.insert(key, val, false)
+	}
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, one by one.
+// Returns keys of inserted entries; entries with already existing keys are left untouched.
+func (this *Ctrie) SetManyIfNotExists(entries map[interface{}]interface{}) []interface{} {
+	inserted := make([]interface{}, 0, len(entries))
+	for key, val := range entries {
+		if this.insert(key, val, true) {
+			inserted = append(inserted, key)
+		}
+	}
+	return inserted
+}
+
+// Retrieves elements under given keys from a single O(1) read-only snapshot of the map.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *Ctrie) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	snapshot := this.snapshot(true)
+	found := make(map[interface{}]interface{}, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if val, ok := snapshot.Get(key); ok {
+			found[key] = val
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys, one by one.
+func (this *Ctrie) RemoveMany(keys []interface{}) {
+	for _, key := range keys {
+		this.Remove(key)
+	}
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), see ConcurrentMap.MarshalJSON.
+// The map is rendered from an O(1) read-only snapshot, so it never blocks writers.
+func (this *Ctrie) MarshalJSON() ([]byte, error) {
+	return marshalJSONEntries(this)
+}
+
+func (this *Ctrie) jsonEntries() []Entry {
+	entries := []Entry{}
+	this.Range(func(key, value interface{}) bool {
+		entries = append(entries, Entry{Key: key, Value: value})
+		return true
+	})
+	sortEntries(entries)
+	return entries
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), see ConcurrentMap.UnmarshalJSON.
+func (this *Ctrie) UnmarshalJSON(data []byte) error {
+	entries, err := decodeJSONEntries(data)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		this.insert(entry.Key, entry.Value, false)
+	}
+	return nil
+}
+
+func (this *Ctrie) insert(key interface{}, val interface{}, onlyIfAbsent bool) bool {
+	hash := ctrieHash(key)
+	for {
+		root := this.readRoot(false)
+		inserted, res := this.iinsert(root, &sNode{key: key, hash: hash, value: val}, 0, nil, root.gen, onlyIfAbsent)
+		if res != restart {
+			return inserted
+		}
+	}
+}
+
+func (this *Ctrie) ilookup(in *iNode, key interface{}, hash uint64, lev uint, parent *iNode, startGen *ctrieGeneration) (interface{}, bool, *ctrieRestart) {
+	main := this.gcasRead(in)
+	switch {
+	case main.cNode != nil:
+		cn := main.cNode
+		flag, pos := flagPos(hash, lev, cn.bmp)
+		if cn.bmp&flag == 0 {
+			return nil, false, nil
+		}
+		switch branch := cn.array[pos].(type) {
+		case *iNode:
+			if this.readOnly || startGen == branch.gen {
+				return this.ilookup(branch, key, hash, lev+ctrieLevelBits, in, startGen)
+			}
+			if this.gcas(in, main, &mainNode{cNode: cn.renewed(startGen, this)}) {
+				return this.ilookup(in, key, hash, lev, parent, startGen)
+			}
+			return nil, false, restart
+		case *sNode:
+			if branch.hash == hash && branch.key == key {
+				return branch.value, true, nil
+			}
+			return nil, false, nil
+		}
+	case main.tNode != nil:
+		if this.readOnly {
+			// Read-only snapshots never get cleaned, the entombed leaf is still a valid content
+			sn := main.tNode
+			if sn.hash == hash && sn.key == key {
+				return sn.value, true, nil
+			}
+			return nil, false, nil
+		}
+		this.clean(parent, lev-ctrieLevelBits)
+		return nil, false, restart
+	case main.lNode != nil:
+		for _, sn := range main.lNode {
+			if sn.key == key {
+				return sn.value, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+	return nil, false, restart
+}
+
+func (this *Ctrie) iinsert(in *iNode, sn *sNode, lev uint, parent *iNode, startGen *ctrieGeneration, onlyIfAbsent bool) (bool, *ctrieRestart) {
+	main := this.gcasRead(in)
+	switch {
+	case main.cNode != nil:
+		cn := main.cNode
+		flag, pos := flagPos(sn.hash, lev, cn.bmp)
+		if cn.bmp&flag == 0 {
+			rn := cn
+			if cn.gen != in.gen {
+				rn = cn.renewed(in.gen, this)
+			}
+			if this.gcas(in, main, &mainNode{cNode: rn.inserted(pos, flag, sn, in.gen)}) {
+				return true, nil
+			}
+			return false, restart
+		}
+		switch branch := cn.array[pos].(type) {
+		case *iNode:
+			if startGen == branch.gen {
+				return this.iinsert(branch, sn, lev+ctrieLevelBits, in, startGen, onlyIfAbsent)
+			}
+			if this.gcas(in, main, &mainNode{cNode: cn.renewed(startGen, this)}) {
+				return this.iinsert(in, sn, lev, parent, startGen, onlyIfAbsent)
+			}
+			return false, restart
+		case *sNode:
+			rn := cn
+			if cn.gen != in.gen {
+				rn = cn.renewed(in.gen, this)
+			}
+			if branch.hash == sn.hash && branch.key == sn.key {
+				if onlyIfAbsent {
+					return false, nil
+				}
+				if this.gcas(in, main, &mainNode{cNode: rn.updated(pos, sn, in.gen)}) {
+					return true, nil
+				}
+				return false, restart
+			}
+			nin := &iNode{gen: in.gen}
+			nin.main.Store(dualNode(branch, sn, lev+ctrieLevelBits, in.gen))
+			if this.gcas(in, main, &mainNode{cNode: rn.updated(pos, nin, in.gen)}) {
+				return true, nil
+			}
+			return false, restart
+		}
+	case main.tNode != nil:
+		this.clean(parent, lev-ctrieLevelBits)
+		return false, restart
+	case main.lNode != nil:
+		list := make([]*sNode, 0, len(main.lNode)+1)
+		for _, x := range main.lNode {
+			if x.key == sn.key {
+				if onlyIfAbsent {
+					return false, nil
+				}
+				continue
+			}
+			list = append(list, x)
+		}
+		list = append(list, sn)
+		if this.gcas(in, main, &mainNode{lNode: list}) {
+			return true, nil
+		}
+		return false, restart
+	}
+	return false, restart
+}
+
+func (this *Ctrie) iremove(in *iNode, key interface{}, hash uint64, lev uint, parent *iNode, startGen *ctrieGeneration) *ctrieRestart {
+	main := this.gcasRead(in)
+	switch {
+	case main.cNode != nil:
+		cn := main.cNode
+		flag, pos := flagPos(hash, lev, cn.bmp)
+		if cn.bmp&flag == 0 {
+			return nil
+		}
+		switch branch := cn.array[pos].(type) {
+		case *iNode:
+			if startGen == branch.gen {
+				return this.iremove(branch, key, hash, lev+ctrieLevelBits, in, startGen)
+			}
+			if this.gcas(in, main, &mainNode{cNode: cn.renewed(startGen, this)}) {
+				return this.iremove(in, key, hash, lev, parent, startGen)
+			}
+			return restart
+		case *sNode:
+			if branch.hash != hash || branch.key != key {
+				return nil
+			}
+			ncn := cn.removed(pos, flag, in.gen)
+			if !this.gcas(in, main, toContracted(ncn, lev)) {
+				return restart
+			}
+			if parent != nil && this.gcasRead(in).tNode != nil {
+				this.cleanParent(parent, in, hash, lev-ctrieLevelBits, startGen)
+			}
+			return nil
+		}
+	case main.tNode != nil:
+		this.clean(parent, lev-ctrieLevelBits)
+		return restart
+	case main.lNode != nil:
+		list := make([]*sNode, 0, len(main.lNode))
+		for _, x := range main.lNode {
+			if x.key != key {
+				list = append(list, x)
+			}
+		}
+		if len(list) == len(main.lNode) {
+			return nil
+		}
+		nmain := &mainNode{lNode: list}
+		if len(list) == 1 {
+			nmain = &mainNode{tNode: list[0]}
+		}
+		if this.gcas(in, main, nmain) {
+			return nil
+		}
+		return restart
+	}
+	return restart
+}
+
+// Compresses the cNode of the `in`, resurrecting entombed branches.
+func (this *Ctrie) clean(in *iNode, lev uint) {
+	main := this.gcasRead(in)
+	if main.cNode != nil {
+		this.gcas(in, main, this.toCompressed(main.cNode, lev, in.gen))
+	}
+}
+
+// Replaces entombed `in` inside of the `parent` by its leaf.
+func (this *Ctrie) cleanParent(parent, in *iNode, hash uint64, lev uint, startGen *ctrieGeneration) {
+	for {
+		main := this.gcasRead(in)
+		pmain := this.gcasRead(parent)
+		if pmain.cNode == nil {
+			return
+		}
+		cn := pmain.cNode
+		flag, pos := flagPos(hash, lev, cn.bmp)
+		if cn.bmp&flag == 0 || cn.array[pos] != in || main.tNode == nil {
+			return
+		}
+		ncn := cn.updated(pos, main.tNode, in.gen)
+		if this.gcas(parent, pmain, toContracted(ncn, lev)) || this.readRoot(false).gen != startGen {
+			return
+		}
+	}
+}
+
+func (this *Ctrie) toCompressed(cn *cNode, lev uint, gen *ctrieGeneration) *mainNode {
+	array := make([]interface{}, len(cn.array))
+	for i, branch := range cn.array {
+		if in, ok := branch.(*iNode); ok {
+			if main := this.gcasRead(in); main.tNode != nil {
+				array[i] = main.tNode
+				continue
+			}
+		}
+		array[i] = branch
+	}
+	return toContracted(&cNode{bmp: cn.bmp, array: array, gen: gen}, lev)
+}
+
+// Entombs a single leaf of a non-root cNode, so that its parent can replace it by the leaf.
+func toContracted(cn *cNode, lev uint) *mainNode {
+	if lev > 0 && len(cn.array) == 1 {
+		if sn, ok := cn.array[0].(*sNode); ok {
+			return &mainNode{tNode: sn}
+		}
+	}
+	return &mainNode{cNode: cn}
+}
+
+// Returns the main node holding two leaves with different keys, branching at the level `lev` or deeper.
+func dualNode(x, y *sNode, lev uint, gen *ctrieGeneration) *mainNode {
+	if lev >= ctrieHashBits {
+		return &mainNode{lNode: []*sNode{x, y}}
+	}
+	xIdx, yIdx := (x.hash>>lev)&ctrieLevelMask, (y.hash>>lev)&ctrieLevelMask
+	bmp := uint32(1)<<xIdx | uint32(1)<<yIdx
+	switch {
+	case xIdx == yIdx:
+		in := &iNode{gen: gen}
+		in.main.Store(dualNode(x, y, lev+ctrieLevelBits, gen))
+		return &mainNode{cNode: &cNode{bmp: bmp, array: []interface{}{in}, gen: gen}}
+	case xIdx < yIdx:
+		return &mainNode{cNode: &cNode{bmp: bmp, array: []interface{}{x, y}, gen: gen}}
+	default:
+		return &mainNode{cNode: &cNode{bmp: bmp, array: []interface{}{y, x}, gen: gen}}
+	}
+}
+
+// Returns the bit of the `hash` branch at the level `lev` and its position in the array of the `bmp`.
+func flagPos(hash uint64, lev uint, bmp uint32) (uint32, int) {
+	flag := uint32(1) << ((hash >> lev) & ctrieLevelMask)
+	return flag, bits.OnesCount32(bmp & (flag - 1))
+}
+
+func (this *cNode) inserted(pos int, flag uint32, branch interface{}, gen *ctrieGeneration) *cNode {
+	array := make([]interface{}, len(this.array)+1)
+	copy(array, this.array[:pos])
+	array[pos] = branch
+	copy(array[pos+1:], this.array[pos:])
+	return &cNode{bmp: this.bmp | flag, array: array, gen: gen}
+}
+
+func (this *cNode) updated(pos int, branch interface{}, gen *ctrieGeneration) *cNode {
+	array := make([]interface{}, len(this.array))
+	copy(array, this.array)
+	array[pos] = branch
+	return &cNode{bmp: this.bmp, array: array, gen: gen}
+}
+
+func (this *cNode) removed(pos int, flag uint32, gen *ctrieGeneration) *cNode {
+	array := make([]interface{}, len(this.array)-1)
+	copy(array, this.array[:pos])
+	copy(array[pos:], this.array[pos+1:])
+	return &cNode{bmp: this.bmp ^ flag, array: array, gen: gen}
+}
+
+// Returns a copy of the cNode whose iNode branches are copied into the generation `gen`.
+func (this *cNode) renewed(gen *ctrieGeneration, ct *Ctrie) *cNode {
+	array := make([]interface{}, len(this.array))
+	for i, branch := range this.array {
+		if in, ok := branch.(*iNode); ok {
+			array[i] = in.copyToGen(gen, ct)
+		} else {
+			array[i] = branch
+		}
+	}
+	return &cNode{bmp: this.bmp, array: array, gen: gen}
+}
+
+// Returns a new iNode of the generation `gen` sharing the main node of this one.
+func (this *iNode) copyToGen(gen *ctrieGeneration, ct *Ctrie) *iNode {
+	in := &iNode{gen: gen}
+	in.main.Store(ct.gcasRead(this))
+	return in
+}
+
+// Generation-compare-and-swap: replaces the main node of the `in` by `new`, if it is still `old`
+// and the root has not moved to another generation meanwhile.
+func (this *Ctrie) gcas(in *iNode, old, new *mainNode) bool {
+	new.prev.Store(old)
+	if in.main.CompareAndSwap(old, new) {
+		this.gcasCommit(in, new)
+		return new.prev.Load() == nil
+	}
+	return false
+}
+
+func (this *Ctrie) gcasRead(in *iNode) *mainNode {
+	main := in.main.Load()
+	if main.prev.Load() == nil {
+		return main
+	}
+	return this.gcasCommit(in, main)
+}
+
+func (this *Ctrie) gcasCommit(in *iNode, main *mainNode) *mainNode {
+	for {
+		root := this.readRoot(true)
+		prev := main.prev.Load()
+		if prev == nil {
+			return main
+		}
+		if prev.failed != nil {
+			// The replacement has failed, roll back to the previous main node
+			if in.main.CompareAndSwap(main, prev.failed) {
+				return prev.failed
+			}
+			main = in.main.Load()
+			continue
+		}
+		if root.gen == in.gen && !this.readOnly {
+			if main.prev.CompareAndSwap(prev, nil) {
+				return main
+			}
+			continue
+		}
+		main.prev.CompareAndSwap(prev, &mainNode{failed: prev})
+		main = in.main.Load()
+	}
+}
+
+// Returns the root iNode, completing or aborting (if `abort`) a pending snapshot.
+func (this *Ctrie) readRoot(abort bool) *iNode {
+	root := this.root.Load()
+	if root == nil {
+		this.root.CompareAndSwap(nil, newCtrieRoot(&ctrieGeneration{}))
+		root = this.root.Load()
+	}
+	if root.in != nil {
+		return root.in
+	}
+	return this.rdcssComplete(abort)
+}
+
+// Replaces the root `old` by `new` if the main node of the `oldIn` is still `expected`.
+func (this *Ctrie) rdcss(old *ctrieRoot, oldIn *iNode, expected *mainNode, newIn *iNode) bool {
+	if old == nil || old.in != oldIn {
+		return false
+	}
+	desc := &rdcssDescriptor{old: old, expected: expected, new: &ctrieRoot{in: newIn}}
+	if this.root.CompareAndSwap(old, &ctrieRoot{desc: desc}) {
+		this.rdcssComplete(false)
+		return desc.committed.Load()
+	}
+	return false
+}
+
+func (this *Ctrie) rdcssComplete(abort bool) *iNode {
+	for {
+		root := this.root.Load()
+		if root.in != nil {
+			return root.in
+		}
+		desc := root.desc
+		if abort {
+			if this.root.CompareAndSwap(root, desc.old) {
+				return desc.old.in
+			}
+			continue
+		}
+		if this.gcasRead(desc.old.in) == desc.expected {
+			if this.root.CompareAndSwap(root, desc.new) {
+				desc.committed.Store(true)
+				return desc.new.in
+			}
+			continue
+		}
+		if this.root.CompareAndSwap(root, desc.old) {
+			return desc.old.in
+		}
+	}
+}
+
+// Calls `fn` for every leaf reachable from the `in`, until `fn` returns false. Must be called on a read-only snapshot.
+func (this *Ctrie) traverse(in *iNode, fn func(key, value interface{}) bool) bool {
+	main := this.gcasRead(in)
+	switch {
+	case main.cNode != nil:
+		for _, branch := range main.cNode.array {
+			switch x := branch.(type) {
+			case *iNode:
+				if !this.traverse(x, fn) {
+					return false
+				}
+			case *sNode:
+				if !fn(x.key, x.value) {
+					return false
+				}
+			}
+		}
+	case main.tNode != nil:
+		return fn(main.tNode.key, main.tNode.value)
+	case main.lNode != nil:
+		for _, sn := range main.lNode {
+			if !fn(sn.key, sn.value) {
+				return false
+			}
+		}
+	}
+	return true
+}
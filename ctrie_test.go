@@ -0,0 +1,221 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestCtrieSnapshotIsolation(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias             string
+		Items                 map[interface{}]interface{}
+		ModifyOriginalFn      func(cm *Ctrie)
+		ModifySnapshotFn      func(cm *Ctrie)
+		ExpectedOriginalItems map[interface{}]interface{}
+		ExpectedSnapshotItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:             "Snapshot of empty map",
+			Items:                 map[interface{}]interface{}{},
+			ModifyOriginalFn:      func(cm *Ctrie) { cm.Set("key1", "stringValue") },
+			ModifySnapshotFn:      func(cm *Ctrie) { cm.Set("key2", 123) },
+			ExpectedOriginalItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedSnapshotItems: map[interface{}]interface{}{"key2": 123},
+		},
+		{
+			TestAlias: "Modifying both original and snapshot",
+			Items:     map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56},
+			ModifyOriginalFn: func(cm *Ctrie) {
+				cm.Set("key1", "otherValue")
+				cm.Remove("key2")
+			},
+			ModifySnapshotFn: func(cm *Ctrie) {
+				cm.Remove("key3")
+				cm.SetIfNotExists("key4", true)
+			},
+			ExpectedOriginalItems: map[interface{}]interface{}{"key1": "otherValue", "key3": 4.56},
+			ExpectedSnapshotItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key4": true},
+		},
+		{
+			TestAlias:             "Many keys spanning several levels",
+			Items:                 intItems(0, 2000),
+			ModifyOriginalFn:      func(cm *Ctrie) { removeRange(cm, 0, 1000) },
+			ModifySnapshotFn:      func(cm *Ctrie) { removeRange(cm, 1000, 2000) },
+			ExpectedOriginalItems: intItems(1000, 2000),
+			ExpectedSnapshotItems: intItems(0, 1000),
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		items := testCase.Items
+		modifyOriginalFn := testCase.ModifyOriginalFn
+		modifySnapshotFn := testCase.ModifySnapshotFn
+		expectedOriginalItems := testCase.ExpectedOriginalItems
+		expectedSnapshotItems := testCase.ExpectedSnapshotItems
+
+		testFn := func(t *testing.T) {
+
+			original := new(Ctrie)
+			for key, value := range items {
+				original.Set(key, value)
+			}
+
+			snapshot := original.Snapshot()
+
+			modifyOriginalFn(original)
+			modifySnapshotFn(snapshot)
+
+			if actualItems := original.Items(); !(reflect.DeepEqual(actualItems, expectedOriginalItems)) {
+				t.Errorf("%s :: original.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedOriginalItems)
+			}
+			if actualItems := snapshot.Items(); !(reflect.DeepEqual(actualItems, expectedSnapshotItems)) {
+				t.Errorf("%s :: snapshot.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedSnapshotItems)
+			}
+			if original.Len() != len(expectedOriginalItems) {
+				t.Errorf("%s :: original.Len() returned %d while expected %d ", testAlias, original.Len(), len(expectedOriginalItems))
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func removeRange(cm *Ctrie, from, to int) {
+	for n := from; n < to; n++ {
+		cm.Remove(n)
+	}
+}
+
+// Every writer inserts its keys in order, so a consistent snapshot holding the n-th key of a writer holds all previous ones.
+func TestCtrieSnapshotConsistencyConcurrently(t *testing.T) {
+
+	const writers, keysPerWriter = 4, 2000
+
+	cm := new(Ctrie)
+
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < keysPerWriter; n++ {
+				cm.Set([2]int{g, n}, n)
+			}
+		}(g)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for snapshots := 0; ; snapshots++ {
+		select {
+		case <-done:
+			if cm.Len() != writers*keysPerWriter {
+				t.Errorf("cm.Len() after concurrent inserts returned %d while expected %d ", cm.Len(), writers*keysPerWriter)
+			}
+			return
+		default:
+		}
+
+		snapshot := cm.Snapshot()
+		snapshot.Set("snapshotOnly", snapshots)
+
+		counts := make([]int, writers)
+		maxKeys := make([]int, writers)
+		snapshot.Range(func(key, value interface{}) bool {
+			if k, ok := key.([2]int); ok {
+				counts[k[0]]++
+				if k[1]+1 > maxKeys[k[0]] {
+					maxKeys[k[0]] = k[1] + 1
+				}
+			}
+			return true
+		})
+		for g := 0; g < writers; g++ {
+			if counts[g] != maxKeys[g] {
+				t.Fatalf("snapshot holds %d keys of writer %d while the greatest key is %d ", counts[g], g, maxKeys[g]-1)
+			}
+		}
+		if _, ok := cm.Get("snapshotOnly"); ok {
+			t.Fatalf("cm.Get(\"snapshotOnly\") returned ok after setting the key to a snapshot ")
+		}
+	}
+}
+
+func TestCtrieBatchAndTryMethods(t *testing.T) {
+
+	cm := new(Ctrie)
+	cm.SetMany(intItems(0, 100))
+
+	if inserted := cm.SetManyIfNotExists(intItems(50, 150)); len(inserted) != 50 {
+		t.Errorf("cm.SetManyIfNotExists(...) inserted %d keys while expected 50 ", len(inserted))
+	}
+	found, missing := cm.GetMany([]interface{}{0, 149, 150})
+	if expected := (map[interface{}]interface{}{0: 0, 149: 149}); !reflect.DeepEqual(found, expected) || !reflect.DeepEqual(missing, []interface{}{150}) {
+		t.Errorf("cm.GetMany(...) returned %#v, %#v while expected %#v, [150] ", found, missing, expected)
+	}
+	cm.RemoveMany([]interface{}{0, 149})
+	if actualItems := cm.Items(); !reflect.DeepEqual(actualItems, intItems(1, 149)) {
+		t.Errorf("cm.Items() after RemoveMany returned %d elements while expected 148 ", len(actualItems))
+	}
+
+	unhashable := []interface{}{"slice"}
+	if err := cm.TrySet(unhashable, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TrySet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, _, err := cm.TryGet(unhashable); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TryGet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, err := cm.TrySetIfNotExists(unhashable, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TrySetIfNotExists(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if err := cm.TryRemove(unhashable); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("cm.TryRemove(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if inserted, err := cm.TrySetIfNotExists(1, 1); inserted || err != nil {
+		t.Errorf("cm.TrySetIfNotExists(1) returned %v, %v while expected false, nil ", inserted, err)
+	}
+}
+
+func TestCtrieJSON(t *testing.T) {
+
+	cm := new(Ctrie)
+	if err := json.Unmarshal([]byte(`{"b": [1, 2], "a": {"key": "value"}}`), cm); err != nil {
+		t.Fatalf("json.Unmarshal(...) returned unexpected error %v ", err)
+	}
+	if err := json.Unmarshal([]byte(`null`), cm); err != nil || cm.Len() != 2 {
+		t.Errorf("json.Unmarshal(null) returned %v and left %d elements while expected a no-op ", err, cm.Len())
+	}
+
+	actualJSON, err := json.Marshal(cm)
+	if err != nil {
+		t.Fatalf("json.Marshal(cm) returned unexpected error %v ", err)
+	}
+	if expected := `{"a":{"key":"value"},"b":[1,2]}`; string(actualJSON) != expected {
+		t.Errorf("json.Marshal(cm) returned %s while expected %s ", actualJSON, expected)
+	}
+}
@@ -0,0 +1,256 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "sync"
+
+// Default values of CustomMap
+const (
+	// Represents minimal number of buckets
+	CUSTOMMAP_MINBUCKETS = 8
+	// Represents average number of entries per bucket, exceeding which doubles the number of buckets
+	CUSTOMMAP_MAXLOADFACTOR = 4
+)
+
+// The CustomMap type represents concurrent-safe map with user-defined key hashing and equality.
+//
+// It allows keys Go maps cannot support, like `[]byte`, structs holding slices or case-insensitive strings.
+// The `hash` function must return equal hashes for keys which are equal according to the `equal` function.
+// Both functions are called with the map locked, so they must not access the map.
+//
+// It has the Get, Set, SetIfNotExists, Remove, Len, batch and JSON methods of ConcurrentMap. Since keys may be unhashable,
+// Items and batch methods take and return slices of entries instead of Go maps, and there are no Try* methods,
+// as any key is accepted. JSON keys are strings, so UnmarshalJSON requires `hash` and `equal` to accept strings.
+// The zero value is not usable, use NewCustom.
+type CustomMap struct {
+	buckets [][]Entry
+	size    int
+	lock    sync.RWMutex
+	hash    func(key interface{}) uint64
+	equal   func(a, b interface{}) bool
+}
+
+// Instantiates and initializes CustomMap with `initCap` capacity, given key hashing and equality functions.
+func NewCustom(initCap int, hash func(key interface{}) uint64, equal func(a, b interface{}) bool) *CustomMap {
+	n := CUSTOMMAP_MINBUCKETS
+	for n*CUSTOMMAP_MAXLOADFACTOR < initCap {
+		n *= 2
+	}
+	return &CustomMap{buckets: make([][]Entry, n), hash: hash, equal: equal}
+}
+
+// Returns the bucket index and the position of the `key` in the bucket, or -1 if there is no such key.
+// Must be called under the lock.
+func (this *CustomMap) find(key interface{}) (int, int) {
+	b := int(this.hash(key) & uint64(len(this.buckets)-1))
+	for i, entry := range this.buckets[b] {
+		if this.equal(entry.Key, key) {
+			return b, i
+		}
+	}
+	return b, -1
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *CustomMap) Get(key interface{}) (interface{}, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	b, i := this.find(key)
+	if i < 0 {
+		return nil, false
+	}
+	return this.buckets[b][i].Value, true
+}
+
+// Sets the given value under the specified key.
+// If an equal key already exists, its value is replaced while the originally stored key is kept.
+func (this *CustomMap) Set(key interface{}, val interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.set(key, val)
+}
+
+// Must be called under the write lock.
+func (this *CustomMap) set(key interface{}, val interface{}) {
+	b, i := this.find(key)
+	if i >= 0 {
+		this.buckets[b][i].Value = val
+		return
+	}
+	this.insert(b, key, val)
+}
+
+// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with an equal key.
+func (this *CustomMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.setIfNotExists(key, val)
+}
+
+// Must be called under the write lock.
+func (this *CustomMap) setIfNotExists(key interface{}, val interface{}) bool {
+	b, i := this.find(key)
+	if i >= 0 {
+		return false
+	}
+	this.insert(b, key, val)
+	return true
+}
+
+// Removes an element from the map.
+func (this *CustomMap) Remove(key interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.remove(key)
+}
+
+// Must be called under the write lock.
+func (this *CustomMap) remove(key interface{}) {
+	b, i := this.find(key)
+	if i < 0 {
+		return
+	}
+	bucket := this.buckets[b]
+	last := len(bucket) - 1
+	bucket[i] = bucket[last]
+	// Let the removed key and value be collected
+	bucket[last] = Entry{}
+	this.buckets[b] = bucket[:last]
+	this.size--
+}
+
+// Returns copy of content as a slice of entries in no particular order.
+func (this *CustomMap) Items() []Entry {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	x := make([]Entry, 0, this.size)
+	for _, bucket := range this.buckets {
+		x = append(x, bucket...)
+	}
+	return x
+}
+
+// Returns number of elements in the map.
+func (this *CustomMap) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return this.size
+}
+
+// Sets all the given entries under a single lock acquisition; later entries win over earlier ones with an equal key.
+func (this *CustomMap) SetMany(entries []Entry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, entry := range entries {
+		this.set(entry.Key, entry.Value)
+	}
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, under a single lock acquisition.
+// Returns keys of inserted entries; entries with already existing keys, including earlier ones of `entries`, are skipped.
+func (this *CustomMap) SetManyIfNotExists(entries []Entry) []interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	inserted := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		if this.setIfNotExists(entry.Key, entry.Value) {
+			inserted = append(inserted, entry.Key)
+		}
+	}
+	return inserted
+}
+
+// Retrieves elements under given keys under a single lock acquisition.
+// Returns found entries, holding the given keys, and keys which have no entry associated, in the order they were given.
+func (this *CustomMap) GetMany(keys []interface{}) ([]Entry, []interface{}) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	found := make([]Entry, 0, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if b, i := this.find(key); i >= 0 {
+			found = append(found, Entry{Key: key, Value: this.buckets[b][i].Value})
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys under a single lock acquisition.
+func (this *CustomMap) RemoveMany(keys []interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, key := range keys {
+		this.remove(key)
+	}
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), see ConcurrentMap.MarshalJSON.
+// Keys are rendered with fmt.Sprint unless they are strings, so e.g. `[]byte` keys become lists of numbers.
+func (this *CustomMap) MarshalJSON() ([]byte, error) {
+	return marshalJSONEntries(this)
+}
+
+func (this *CustomMap) jsonEntries() []Entry {
+	entries := this.Items()
+	sortEntries(entries)
+	return entries
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), see ConcurrentMap.UnmarshalJSON.
+// Keys are strings, which `hash` and `equal` must accept.
+func (this *CustomMap) UnmarshalJSON(data []byte) error {
+	entries, err := decodeJSONEntries(data)
+	if err != nil {
+		return err
+	}
+	this.SetMany(entries)
+	return nil
+}
+
+// Appends new entry to the bucket `b`, growing the buckets if needed. Must be called under the write lock.
+func (this *CustomMap) insert(b int, key interface{}, val interface{}) {
+	this.buckets[b] = append(this.buckets[b], Entry{Key: key, Value: val})
+	this.size++
+	if this.size > len(this.buckets)*CUSTOMMAP_MAXLOADFACTOR {
+		this.grow()
+	}
+}
+
+// Doubles the number of buckets, redistributing entries. Must be called under the write lock.
+func (this *CustomMap) grow() {
+	buckets := make([][]Entry, len(this.buckets)*2)
+	mask := uint64(len(buckets) - 1)
+	for _, bucket := range this.buckets {
+		for _, entry := range bucket {
+			b := this.hash(entry.Key) & mask
+			buckets[b] = append(buckets[b], entry)
+		}
+	}
+	this.buckets = buckets
+}
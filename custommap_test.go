@@ -0,0 +1,220 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+type sliceKey struct {
+	Path []string
+}
+
+func hashSliceKey(key interface{}) uint64 {
+	return HashFoldString(fmt.Sprint(key.(sliceKey).Path))
+}
+
+func equalSliceKey(a, b interface{}) bool {
+	return reflect.DeepEqual(a.(sliceKey).Path, b.(sliceKey).Path)
+}
+
+func TestCustomMapSetGetCycle(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Cm            *CustomMap
+		SetAKey       interface{}
+		SetAValue     interface{}
+		GetAKey       interface{}
+		ExpectedValue interface{}
+		ExpectedOk    bool
+	}{
+		{
+			TestAlias:     "Bytes key and Get equal key",
+			Cm:            NewCustom(0, HashBytes, EqualBytes),
+			SetAKey:       []byte("key"),
+			SetAValue:     123,
+			GetAKey:       []byte("key"),
+			ExpectedValue: 123,
+			ExpectedOk:    true,
+		},
+		{
+			TestAlias:     "Bytes key and Get other key",
+			Cm:            NewCustom(0, HashBytes, EqualBytes),
+			SetAKey:       []byte("key"),
+			SetAValue:     123,
+			GetAKey:       []byte("key2"),
+			ExpectedValue: nil,
+			ExpectedOk:    false,
+		},
+		{
+			TestAlias:     "Case-insensitive key and Get key in other case",
+			Cm:            NewCustom(0, HashFoldString, EqualFoldString),
+			SetAKey:       "Content-Type",
+			SetAValue:     "text/plain",
+			GetAKey:       "CONTENT-TYPE",
+			ExpectedValue: "text/plain",
+			ExpectedOk:    true,
+		},
+		{
+			TestAlias:     "Case-insensitive key and Get key with folded rune",
+			Cm:            NewCustom(0, HashFoldString, EqualFoldString),
+			SetAKey:       "ſtate",
+			SetAValue:     "value",
+			GetAKey:       "STATE",
+			ExpectedValue: "value",
+			ExpectedOk:    true,
+		},
+		{
+			TestAlias:     "Struct key holding a slice",
+			Cm:            NewCustom(0, hashSliceKey, equalSliceKey),
+			SetAKey:       sliceKey{Path: []string{"a", "b"}},
+			SetAValue:     4.56,
+			GetAKey:       sliceKey{Path: []string{"a", "b"}},
+			ExpectedValue: 4.56,
+			ExpectedOk:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := testCase.Cm
+		setAKey := testCase.SetAKey
+		setAValue := testCase.SetAValue
+		getAKey := testCase.GetAKey
+		expectedValue := testCase.ExpectedValue
+		expectedOk := testCase.ExpectedOk
+
+		testFn := func(t *testing.T) {
+
+			cm.Set(setAKey, setAValue)
+
+			actualValue, actualOk := cm.Get(getAKey)
+
+			if !(reflect.DeepEqual(actualValue, expectedValue)) || actualOk != expectedOk {
+				t.Errorf("%s :: cm.Get(%#v) after cm.Set(%#v, %#v) returned %#v, %v while expected %#v, %v ", testAlias, getAKey, setAKey, setAValue, actualValue, actualOk, expectedValue, expectedOk)
+			}
+
+			if ok := cm.SetIfNotExists(getAKey, "otherValue"); ok == expectedOk {
+				t.Errorf("%s :: cm.SetIfNotExists(%#v, ...) returned %v while expected %v ", testAlias, getAKey, ok, !expectedOk)
+			}
+
+			cm.Remove(getAKey)
+			if _, ok := cm.Get(getAKey); ok {
+				t.Errorf("%s :: cm.Get(%#v) after cm.Remove returned ok ", testAlias, getAKey)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestCustomMapItemsConcurrently(t *testing.T) {
+
+	cm := NewCustom(0, HashBytes, EqualBytes)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 500; n++ {
+				key := []byte(fmt.Sprintf("%d-%d", g, n))
+				cm.Set(key, n)
+				if n%2 == 0 {
+					cm.Remove(key)
+				}
+				cm.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	actualKeys := []string{}
+	for _, entry := range cm.Items() {
+		actualKeys = append(actualKeys, string(entry.Key.([]byte)))
+	}
+	sort.Strings(actualKeys)
+
+	expectedKeys := []string{}
+	for g := 0; g < 4; g++ {
+		for n := 1; n < 500; n += 2 {
+			expectedKeys = append(expectedKeys, fmt.Sprintf("%d-%d", g, n))
+		}
+	}
+	sort.Strings(expectedKeys)
+
+	if !(reflect.DeepEqual(actualKeys, expectedKeys)) {
+		t.Errorf("cm.Items() after concurrent modifications returned %d keys while expected %d ", len(actualKeys), len(expectedKeys))
+	}
+	if cm.Len() != len(expectedKeys) {
+		t.Errorf("cm.Len() after concurrent modifications returned %d while expected %d ", cm.Len(), len(expectedKeys))
+	}
+}
+
+func TestCustomMapBatch(t *testing.T) {
+
+	cm := NewCustom(0, HashFoldString, EqualFoldString)
+	cm.SetMany([]Entry{{Key: "Alpha", Value: 1}, {Key: "beta", Value: 2}, {Key: "ALPHA", Value: 3}})
+
+	inserted := cm.SetManyIfNotExists([]Entry{{Key: "alpha", Value: 4}, {Key: "Gamma", Value: 5}})
+	if !reflect.DeepEqual(inserted, []interface{}{"Gamma"}) {
+		t.Errorf("cm.SetManyIfNotExists(...) returned %#v while expected only \"Gamma\" inserted ", inserted)
+	}
+
+	found, missing := cm.GetMany([]interface{}{"ALPHA", "delta", "GAMMA"})
+	if expected := []Entry{{Key: "ALPHA", Value: 3}, {Key: "GAMMA", Value: 5}}; !reflect.DeepEqual(found, expected) {
+		t.Errorf("cm.GetMany(...) found %#v while expected %#v ", found, expected)
+	}
+	if !reflect.DeepEqual(missing, []interface{}{"delta"}) {
+		t.Errorf("cm.GetMany(...) missed %#v while expected \"delta\" ", missing)
+	}
+
+	cm.RemoveMany([]interface{}{"BETA", "gamma"})
+	if cm.Len() != 1 {
+		t.Errorf("cm.Len() after cm.RemoveMany(...) returned %d while expected 1 ", cm.Len())
+	}
+}
+
+func TestCustomMapJSON(t *testing.T) {
+
+	cm := NewCustom(0, HashFoldString, EqualFoldString)
+	if err := json.Unmarshal([]byte(`{"b": 1, "A": {"key": "value"}, "a": 2}`), cm); err != nil {
+		t.Fatalf("json.Unmarshal(...) returned unexpected error %v ", err)
+	}
+	if cm.Len() != 2 {
+		t.Errorf("cm.Len() returned %d while expected keys differing only in case to be merged ", cm.Len())
+	}
+	if err := json.Unmarshal([]byte(`null`), cm); err != nil || cm.Len() != 2 {
+		t.Errorf("json.Unmarshal(null) returned %v and left %d elements while expected a no-op ", err, cm.Len())
+	}
+
+	cm.Remove("a")
+	cm.Set("A", MakeConcurrentCopy(map[interface{}]interface{}{"key": "value"}))
+	actualJSON, err := json.Marshal(cm)
+	if err != nil {
+		t.Fatalf("json.Marshal(cm) returned unexpected error %v ", err)
+	}
+	if expected := `{"A":{"key":"value"},"b":1}`; string(actualJSON) != expected {
+		t.Errorf("json.Marshal(cm) returned %s while expected %s ", actualJSON, expected)
+	}
+}
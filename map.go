@@ -14,7 +14,10 @@
 
 package concurrentmap
 
-import "sync"
+import (
+	"reflect"
+	"sync"
+)
 
 // Default values
 const (
@@ -29,6 +32,25 @@ const (
 type ConcurrentMap struct {
 	items map[interface{}]interface{}
 	lock  sync.RWMutex
+	// Restricts type of keys, see WithKeyType
+	keyType reflect.Type
+	// Collects statistics if not nil, see WithStats
+	stats *mapStats
+	// Interceptors and their composed chain if not nil, see WithInterceptors
+	interceptors []Interceptor
+	invoke       Invoker
+	// Rejects modifications once set, see Freeze
+	frozen bool
+	// String keys in order if not nil, built once by the first Namespace
+	prefixes   *prefixIndex
+	prefixOnce sync.Once
+	// Keys in insertion order if not nil, see WithInsertionOrder
+	order *OrderedMap
+	// Keys indexed by position if not nil and the lock serializing its builds, see Scan
+	dense     *denseIndex
+	denseLock sync.Mutex
+	// Builds the dense index at construction, see WithScanIndex
+	scanIndex bool
 }
 
 // Private factory. It assigns items and set up RWMutex
@@ -36,6 +58,31 @@ func newConcurrentMap(items map[interface{}]interface{}) *ConcurrentMap {
 	return &ConcurrentMap{items: items, lock: sync.RWMutex{}}
 }
 
+// Private factory of a map configured the same way as `this`, e.g. for clones.
+// If `this` keeps insertion order, the new map keeps the order of `keys`, which must be the keys of `items`.
+func (this *ConcurrentMap) newLike(items map[interface{}]interface{}, keys []interface{}) *ConcurrentMap {
+	cm := newConcurrentMap(items)
+	if this != nil {
+		cm.keyType = this.keyType
+		if this.stats != nil {
+			cm.stats = &mapStats{}
+		}
+		if this.interceptors != nil {
+			cm.setInterceptors(this.interceptors)
+		}
+		if this.order != nil {
+			cm.order = NewOrdered()
+			for _, key := range keys {
+				cm.order.set(key, nil)
+			}
+		}
+		if this.scanIndex {
+			WithScanIndex()(cm)
+		}
+	}
+	return cm
+}
+
 // Generic factory. Instantiates and initializes ConcurrentMap with `initCap` capacity.
 func New(initCap int) *ConcurrentMap {
 	items := make(map[interface{}]interface{}, initCap)
@@ -52,70 +99,173 @@ func MakeConcurrentCopy(m map[interface{}]interface{}) *ConcurrentMap {
 }
 
 // Makes Concurrent copy of the `m` recursively.
-// In case the value is of type `map[interface{}]interface{}` - it converts it into ConcurrentMap recursively as well.
+// In case the value is a map of any type - it converts it into ConcurrentMap recursively as well.
+// Maps inside slices, arrays and pointers are converted too, see ConvertRecursively for details.
+//
+// It panics with *CycleError if `m` references itself, directly or through nested values.
 func MakeRecursivelyConcurrentCopy(m map[interface{}]interface{}) *ConcurrentMap {
-	items := make(map[interface{}]interface{}, len(m))
-	for key, value := range m {
-		if x, ok := (value).(map[interface{}]interface{}); ok {
-			items[key] = MakeRecursivelyConcurrentCopy(x)
-		} else {
-			items[key] = value
-		}
+	cm, err := ConvertRecursively(m)
+	if err != nil {
+		panic(err)
 	}
-	return newConcurrentMap(items)
+	return cm
 }
 
 // Retrieves an element from map under given key.
 // Returns false in case there is no entry associated with the key.
+// Panics if the key is unhashable or an interceptor rejects the lookup, see TryGet.
 func (this *ConcurrentMap) Get(key interface{}) (interface{}, bool) {
-	this.lock.RLock()
+	if this.interceptors != nil {
+		op := &Operation{Kind: OperationGet, Key: key}
+		this.mustIntercept(op)
+		return op.Value, op.Found
+	}
+	return this.get(key)
+}
+
+func (this *ConcurrentMap) get(key interface{}) (interface{}, bool) {
+	this.readLock()
 	defer this.lock.RUnlock()
 
 	val, ok := this.items[key]
+	if this.stats != nil {
+		this.stats.observeGet(ok)
+	}
 	return val, ok
 }
 
 // Sets the given value under the specified key.
+// Panics if the key is unhashable or not of the type set by WithKeyType, if an interceptor rejects the write,
+// or with ErrFrozen if the map is frozen, see TrySet.
 func (this *ConcurrentMap) Set(key interface{}, val interface{}) {
-	this.lock.Lock()
+	if this.interceptors != nil {
+		this.mustIntercept(&Operation{Kind: OperationSet, Key: key, Value: val})
+		return
+	}
+	this.mustCheckKey(key)
+	if err := this.set(key, val); err != nil {
+		panic(err)
+	}
+}
+
+func (this *ConcurrentMap) set(key interface{}, val interface{}) error {
+	this.writeLock()
 	defer this.lock.Unlock()
 
+	if this.frozen {
+		return ErrFrozen
+	}
+
 	if this.items == nil {
 		// we would need atleast one element in map
 		this.items = make(map[interface{}]interface{}, DEFAULT_ONSETCAPACITY)
 	}
 
 	this.items[key] = val
+	this.keyAdded(key)
+	if this.stats != nil {
+		this.stats.sets.Add(1)
+	}
+	return nil
 }
 
 // Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
 // Returns false and does nothing, in case there is already an entry with the same key.
+// Panics in the same cases as Set, even if the key exists, see TrySetIfNotExists.
 func (this *ConcurrentMap) SetIfNotExists(key interface{}, val interface{}) bool {
-	this.lock.Lock()
+	if this.interceptors != nil {
+		op := &Operation{Kind: OperationSetIfNotExists, Key: key, Value: val}
+		this.mustIntercept(op)
+		return op.Found
+	}
+	this.mustCheckKey(key)
+	isSet, err := this.setIfNotExists(key, val)
+	if err != nil {
+		panic(err)
+	}
+	return isSet
+}
+
+func (this *ConcurrentMap) setIfNotExists(key interface{}, val interface{}) (bool, error) {
+	this.writeLock()
 	defer this.lock.Unlock()
 
+	if this.frozen {
+		return false, ErrFrozen
+	}
+
 	if _, ok := this.items[key]; !ok {
 		if this.items == nil {
 			// we would need atleast one element in map
 			this.items = make(map[interface{}]interface{}, DEFAULT_ONSETCAPACITY)
 		}
 		this.items[key] = val
-		return true
+		this.keyAdded(key)
+		if this.stats != nil {
+			this.stats.sets.Add(1)
+		}
+		return true, nil
 	}
-	return false
+	return false, nil
 }
 
 // Removes an element from the map.
+// Panics if the key is unhashable, if an interceptor rejects the removal, or with ErrFrozen if the map is frozen,
+// see TryRemove. The key type set by WithKeyType is not checked, as a key of another type is simply absent.
 func (this *ConcurrentMap) Remove(key interface{}) {
-	this.lock.Lock()
+	if this.interceptors != nil {
+		this.mustIntercept(&Operation{Kind: OperationRemove, Key: key})
+		return
+	}
+	if err := this.remove(key); err != nil {
+		panic(err)
+	}
+}
+
+func (this *ConcurrentMap) remove(key interface{}) error {
+	this.writeLock()
 	defer this.lock.Unlock()
 
+	if this.frozen {
+		return ErrFrozen
+	}
 	delete(this.items, key)
+	this.keyRemoved(key)
+	if this.stats != nil {
+		this.stats.removes.Add(1)
+	}
+	return nil
+}
+
+// Maintains the indexes kept by options and helpers of the map for the `key` just set. Must be called under the write lock.
+func (this *ConcurrentMap) keyAdded(key interface{}) {
+	if this.prefixes != nil {
+		this.indexKey(key)
+	}
+	if this.order != nil {
+		this.order.Set(key, nil)
+	}
+	if this.dense != nil {
+		this.dense.add(key)
+	}
+}
+
+// Maintains the indexes kept by options and helpers of the map for the `key` just removed. Must be called under the write lock.
+func (this *ConcurrentMap) keyRemoved(key interface{}) {
+	if this.prefixes != nil {
+		this.unindexKey(key)
+	}
+	if this.order != nil {
+		this.order.Remove(key)
+	}
+	if this.dense != nil {
+		this.dense.remove(key)
+	}
 }
 
 // Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
 func (this *ConcurrentMap) Items() map[interface{}]interface{} {
-	this.lock.RLock()
+	this.readLock()
 	defer this.lock.RUnlock()
 	x := make(map[interface{}]interface{}, len(this.items))
 	for key, value := range this.items {
@@ -123,3 +273,11 @@ func (this *ConcurrentMap) Items() map[interface{}]interface{} {
 	}
 	return x
 }
+
+// Returns number of elements in the map.
+func (this *ConcurrentMap) Len() int {
+	this.readLock()
+	defer this.lock.RUnlock()
+
+	return len(this.items)
+}
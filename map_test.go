@@ -106,6 +106,16 @@ func TestMakeRecursivelyConcurrentCopyItemsCycle(t *testing.T) {
 			OriginalMap:   map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "Map": map[interface{}]interface{}{"key1": "stringValue", "key2": 123}},
 			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123})},
 		},
+		{
+			TestAlias:     "Converting nested map of other type into a concurrent map",
+			OriginalMap:   map[interface{}]interface{}{"key1": "stringValue", "Map": map[string]interface{}{"key1": "stringValue", "key2": 123}},
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "Map": MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue", "key2": 123})},
+		},
+		{
+			TestAlias:     "Converting slice of nested maps into a concurrent map",
+			OriginalMap:   map[interface{}]interface{}{"Slice": []interface{}{map[interface{}]interface{}{"key1": "stringValue"}, 123}},
+			ExpectedItems: map[interface{}]interface{}{"Slice": []interface{}{MakeConcurrentCopy(map[interface{}]interface{}{"key1": "stringValue"}), 123}},
+		},
 	}
 
 	for _, testCase := range testCases {
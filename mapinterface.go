@@ -0,0 +1,47 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+// The Map interface represents the basic concurrent-safe map API shared by map implementations of the package,
+// so they can be switched per workload without touching call sites.
+//
+// All methods must be safe for concurrent use. Keys must be of comparable types as for general map[interface{}]interface{}.
+// Implementations can be verified by the conformance suite of the `maptest` package.
+type Map interface {
+	// Retrieves an element from map under given key. Returns false in case there is no entry associated with the key.
+	Get(key interface{}) (interface{}, bool)
+	// Sets the given value under the specified key.
+	Set(key interface{}, val interface{})
+	// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+	// Returns false and does nothing, in case there is already an entry with the same key.
+	SetIfNotExists(key interface{}, val interface{}) bool
+	// Removes an element from the map.
+	Remove(key interface{})
+	// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+	Items() map[interface{}]interface{}
+}
+
+// Implementations of Map
+var (
+	_ Map = (*ConcurrentMap)(nil)
+	_ Map = (*SyncMap)(nil)
+	_ Map = (*ShardedMap)(nil)
+	_ Map = (*CopyOnWriteMap)(nil)
+	_ Map = (*Ctrie)(nil)
+	_ Map = (*VersionedMap)(nil)
+	_ Map = (*OverlayMap)(nil)
+	_ Map = (*OrderedMap)(nil)
+	_ Map = (*SortedMap)(nil)
+)
@@ -0,0 +1,104 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+	"github.com/gopot/concurrent-map/maptest"
+)
+
+func TestMapConformance(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias string
+		Factory   maptest.Factory
+	}{
+		{
+			TestAlias: "ConcurrentMap",
+			Factory:   func(m map[interface{}]interface{}) Map { return MakeConcurrentCopy(m) },
+		},
+		{
+			TestAlias: "SyncMap",
+			Factory:   func(m map[interface{}]interface{}) Map { return NewSyncMap(m) },
+		},
+		{
+			TestAlias: "ShardedMap",
+			Factory: func(m map[interface{}]interface{}) Map {
+				cm := NewSharded(4, len(m))
+				cm.SetMany(m)
+				return cm
+			},
+		},
+		{
+			TestAlias: "CopyOnWriteMap",
+			Factory:   func(m map[interface{}]interface{}) Map { return NewCopyOnWrite(m) },
+		},
+		{
+			TestAlias: "Ctrie",
+			Factory: func(m map[interface{}]interface{}) Map {
+				cm := NewCtrie()
+				for key, value := range m {
+					cm.Set(key, value)
+				}
+				return cm
+			},
+		},
+		{
+			TestAlias: "VersionedMap",
+			Factory:   func(m map[interface{}]interface{}) Map { return NewVersioned(m) },
+		},
+		{
+			TestAlias: "OverlayMap",
+			Factory:   func(m map[interface{}]interface{}) Map { return Overlay(New(0), MakeConcurrentCopy(m)) },
+		},
+		{
+			TestAlias: "OrderedMap",
+			Factory: func(m map[interface{}]interface{}) Map {
+				cm := NewOrdered()
+				for key, value := range m {
+					cm.Set(key, value)
+				}
+				return cm
+			},
+		},
+		{
+			TestAlias: "SortedMap",
+			Factory: func(m map[interface{}]interface{}) Map {
+				// Orders keys of any types by type and then by Go-syntax representation
+				cm := NewSorted(func(a, b interface{}) int {
+					return strings.Compare(fmt.Sprintf("%T %#v", a, a), fmt.Sprintf("%T %#v", b, b))
+				})
+				for key, value := range m {
+					cm.Set(key, value)
+				}
+				return cm
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		factory := testCase.Factory
+
+		testFn := func(t *testing.T) {
+			maptest.Run(t, factory)
+		}
+		t.Run(testCase.TestAlias, testFn)
+	}
+
+}
@@ -0,0 +1,27 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package maptest provides the conformance test suite for implementations of concurrentmap.Map.
+//
+// An implementation is verified by running the suite from its own test with a factory of pre-filled maps:
+//
+//	func TestConformance(t *testing.T) {
+//		maptest.Run(t, func(m map[interface{}]interface{}) concurrentmap.Map {
+//			return NewMyMap(m)
+//		})
+//	}
+//
+// The suite covers Get, Set, SetIfNotExists, Remove and Items semantics as well as concurrent access,
+// so it is worth to be run with `-race` flag.
+package maptest
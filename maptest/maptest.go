@@ -0,0 +1,426 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package maptest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	concurrentmap "github.com/gopot/concurrent-map"
+)
+
+// Default values of the suite
+const (
+	// Represents number of goroutines of concurrent tests
+	CONCURRENT_GOROUTINES = 8
+	// Represents number of operations each goroutine of concurrent tests performs
+	CONCURRENT_OPERATIONS = 500
+)
+
+// Represents a factory of the Map implementation under test.
+// It must return a new map holding the copy of `m`, which is empty or nil for an empty map.
+type Factory func(m map[interface{}]interface{}) concurrentmap.Map
+
+// Runs the whole conformance suite against maps built by `newMap`, each test as a subtest of `t`.
+func Run(t *testing.T, newMap Factory) {
+	t.Run("Get", func(t *testing.T) { TestGet(t, newMap) })
+	t.Run("SetGetCycle", func(t *testing.T) { TestSetGetCycle(t, newMap) })
+	t.Run("SetItemsCycle", func(t *testing.T) { TestSetItemsCycle(t, newMap) })
+	t.Run("SetIfNotExists", func(t *testing.T) { TestSetIfNotExists(t, newMap) })
+	t.Run("RemoveItemsCycle", func(t *testing.T) { TestRemoveItemsCycle(t, newMap) })
+	t.Run("ItemsIsCopy", func(t *testing.T) { TestItemsIsCopy(t, newMap) })
+	t.Run("Concurrent", func(t *testing.T) { TestConcurrent(t, newMap) })
+}
+
+// Initial contents of maps the suite starts with
+var (
+	emptyItems = map[interface{}]interface{}{}
+	flatItems  = map[interface{}]interface{}{"key1": "stringValue", "key2": 123, 3: 4.56, struct{ A, B int }{1, 2}: nil}
+)
+
+// Verifies Get of existing and non-existing keys.
+func TestGet(t *testing.T, newMap Factory) {
+
+	testCases := []struct {
+		TestAlias     string
+		Items         map[interface{}]interface{}
+		GetAKey       interface{}
+		ExpectedValue interface{}
+		ExpectedOk    bool
+	}{
+		{
+			TestAlias:     "Get existing string key",
+			Items:         flatItems,
+			GetAKey:       "key2",
+			ExpectedValue: 123,
+			ExpectedOk:    true,
+		},
+		{
+			TestAlias:     "Get existing int key",
+			Items:         flatItems,
+			GetAKey:       3,
+			ExpectedValue: 4.56,
+			ExpectedOk:    true,
+		},
+		{
+			TestAlias:     "Get existing key holding nil",
+			Items:         flatItems,
+			GetAKey:       struct{ A, B int }{1, 2},
+			ExpectedValue: nil,
+			ExpectedOk:    true,
+		},
+		{
+			TestAlias:     "Get key of other type",
+			Items:         flatItems,
+			GetAKey:       int64(3),
+			ExpectedValue: nil,
+			ExpectedOk:    false,
+		},
+		{
+			TestAlias:     "Get non-existing key",
+			Items:         flatItems,
+			GetAKey:       "key3",
+			ExpectedValue: nil,
+			ExpectedOk:    false,
+		},
+		{
+			TestAlias:     "Get non-existing key of empty map",
+			Items:         emptyItems,
+			GetAKey:       "key3",
+			ExpectedValue: nil,
+			ExpectedOk:    false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := newMap(copyItems(testCase.Items))
+		getAKey := testCase.GetAKey
+		expectedValue := testCase.ExpectedValue
+		expectedOk := testCase.ExpectedOk
+
+		testFn := func(t *testing.T) {
+
+			actualValue, actualOk := cm.Get(getAKey)
+
+			if !(reflect.DeepEqual(actualValue, expectedValue)) || actualOk != expectedOk {
+				t.Errorf("%s :: cm.Get(%#v) returned %#v, %v while expected %#v, %v ", testAlias, getAKey, actualValue, actualOk, expectedValue, expectedOk)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+// Verifies Get after Set of existing and non-existing keys.
+func TestSetGetCycle(t *testing.T, newMap Factory) {
+
+	testCases := []struct {
+		TestAlias string
+		Items     map[interface{}]interface{}
+		SetAKey   interface{}
+		SetAValue interface{}
+	}{
+		{
+			TestAlias: "Set existing key",
+			Items:     flatItems,
+			SetAKey:   "key2",
+			SetAValue: 321,
+		},
+		{
+			TestAlias: "Set non-existing key",
+			Items:     flatItems,
+			SetAKey:   "key3",
+			SetAValue: 4.56,
+		},
+		{
+			TestAlias: "Set non-existing key of empty map",
+			Items:     emptyItems,
+			SetAKey:   "key3",
+			SetAValue: 4.56,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := newMap(copyItems(testCase.Items))
+		setAKey := testCase.SetAKey
+		setAValue := testCase.SetAValue
+
+		testFn := func(t *testing.T) {
+
+			cm.Set(setAKey, setAValue)
+
+			actualValue, actualOk := cm.Get(setAKey)
+
+			if !(reflect.DeepEqual(actualValue, setAValue)) || !actualOk {
+				t.Errorf("%s :: cm.Get(%#v) after cm.Set(%#v, %#v) returned %#v, %v while expected %#v, true ", testAlias, setAKey, setAKey, setAValue, actualValue, actualOk, setAValue)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+// Verifies Items after Set of existing and non-existing keys.
+func TestSetItemsCycle(t *testing.T, newMap Factory) {
+
+	testCases := []struct {
+		TestAlias     string
+		Items         map[interface{}]interface{}
+		SetAKey       interface{}
+		SetAValue     interface{}
+		ExpectedItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "Set existing key",
+			Items:         map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			SetAKey:       "key2",
+			SetAValue:     321,
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 321},
+		},
+		{
+			TestAlias:     "Set non-existing key",
+			Items:         map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			SetAKey:       "key3",
+			SetAValue:     4.56,
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56},
+		},
+		{
+			TestAlias:     "Set non-existing key of empty map",
+			Items:         emptyItems,
+			SetAKey:       "key3",
+			SetAValue:     4.56,
+			ExpectedItems: map[interface{}]interface{}{"key3": 4.56},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := newMap(copyItems(testCase.Items))
+		setAKey := testCase.SetAKey
+		setAValue := testCase.SetAValue
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			cm.Set(setAKey, setAValue)
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() after cm.Set(%#v, %#v) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, setAKey, setAValue, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+// Verifies result of SetIfNotExists and Items after it for existing and non-existing keys.
+func TestSetIfNotExists(t *testing.T, newMap Factory) {
+
+	testCases := []struct {
+		TestAlias     string
+		Items         map[interface{}]interface{}
+		SetAKey       interface{}
+		SetAValue     interface{}
+		ExpectedOk    bool
+		ExpectedItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "SetIfNotExists existing key",
+			Items:         map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			SetAKey:       "key2",
+			SetAValue:     321,
+			ExpectedOk:    false,
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+		},
+		{
+			TestAlias:     "SetIfNotExists existing key holding nil",
+			Items:         map[interface{}]interface{}{"key1": nil},
+			SetAKey:       "key1",
+			SetAValue:     321,
+			ExpectedOk:    false,
+			ExpectedItems: map[interface{}]interface{}{"key1": nil},
+		},
+		{
+			TestAlias:     "SetIfNotExists non-existing key",
+			Items:         map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			SetAKey:       "key3",
+			SetAValue:     4.56,
+			ExpectedOk:    true,
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key3": 4.56},
+		},
+		{
+			TestAlias:     "SetIfNotExists non-existing key of empty map",
+			Items:         emptyItems,
+			SetAKey:       "key3",
+			SetAValue:     4.56,
+			ExpectedOk:    true,
+			ExpectedItems: map[interface{}]interface{}{"key3": 4.56},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := newMap(copyItems(testCase.Items))
+		setAKey := testCase.SetAKey
+		setAValue := testCase.SetAValue
+		expectedOk := testCase.ExpectedOk
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			actualOk := cm.SetIfNotExists(setAKey, setAValue)
+
+			if actualOk != expectedOk {
+				t.Errorf("%s :: cm.SetIfNotExists(%#v, %#v) returned %v while expected %v ", testAlias, setAKey, setAValue, actualOk, expectedOk)
+			}
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() after cm.SetIfNotExists(%#v, %#v) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, setAKey, setAValue, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+// Verifies Get and Items after Remove of existing and non-existing keys.
+func TestRemoveItemsCycle(t *testing.T, newMap Factory) {
+
+	testCases := []struct {
+		TestAlias     string
+		Items         map[interface{}]interface{}
+		RemoveAKey    interface{}
+		ExpectedItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:     "Remove existing key",
+			Items:         map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			RemoveAKey:    "key2",
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue"},
+		},
+		{
+			TestAlias:     "Remove non-existing key",
+			Items:         map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			RemoveAKey:    "key3",
+			ExpectedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+		},
+		{
+			TestAlias:     "Remove non-existing key of empty map",
+			Items:         emptyItems,
+			RemoveAKey:    "key3",
+			ExpectedItems: map[interface{}]interface{}{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := newMap(copyItems(testCase.Items))
+		removeAKey := testCase.RemoveAKey
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			cm.Remove(removeAKey)
+
+			if actualValue, actualOk := cm.Get(removeAKey); actualOk {
+				t.Errorf("%s :: cm.Get(%#v) after cm.Remove(%#v) returned %#v, true while expected nil, false ", testAlias, removeAKey, removeAKey, actualValue)
+			}
+
+			actualItems := cm.Items()
+
+			if !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() after cm.Remove(%#v) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, removeAKey, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+// Verifies that neither the map passed to the factory nor the result of Items are shared with the map.
+func TestItemsIsCopy(t *testing.T, newMap Factory) {
+	items := map[interface{}]interface{}{"key1": "stringValue"}
+	cm := newMap(items)
+
+	items["key2"] = 123
+	if actualValue, actualOk := cm.Get("key2"); actualOk {
+		t.Errorf("cm.Get(\"key2\") returned %#v, true after modifying the map passed to the factory ", actualValue)
+	}
+
+	cm.Items()["key3"] = 4.56
+	if actualValue, actualOk := cm.Get("key3"); actualOk {
+		t.Errorf("cm.Get(\"key3\") returned %#v, true after modifying result of cm.Items() ", actualValue)
+	}
+}
+
+// Verifies concurrent Set, SetIfNotExists, Get, Remove and Items on disjoint and shared keys.
+func TestConcurrent(t *testing.T, newMap Factory) {
+	cm := newMap(nil)
+
+	var wg sync.WaitGroup
+	inserted := make([]int, CONCURRENT_GOROUTINES)
+	for g := 0; g < CONCURRENT_GOROUTINES; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < CONCURRENT_OPERATIONS; n++ {
+				key := fmt.Sprintf("%d-%d", g, n)
+				cm.Set(key, n)
+				if actualValue, actualOk := cm.Get(key); !actualOk || actualValue != n {
+					t.Errorf("cm.Get(%q) right after cm.Set returned %#v, %v while expected %d, true ", key, actualValue, actualOk, n)
+				}
+				if n%2 == 0 {
+					cm.Remove(key)
+				}
+				if cm.SetIfNotExists(fmt.Sprintf("shared-%d", n), g) {
+					inserted[g]++
+				}
+				if n%100 == 0 {
+					cm.Items()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	totalInserted := 0
+	for _, n := range inserted {
+		totalInserted += n
+	}
+	if totalInserted != CONCURRENT_OPERATIONS {
+		t.Errorf("cm.SetIfNotExists returned true %d times for %d shared keys ", totalInserted, CONCURRENT_OPERATIONS)
+	}
+
+	expectedLen := CONCURRENT_GOROUTINES*CONCURRENT_OPERATIONS/2 + CONCURRENT_OPERATIONS
+	if actualLen := len(cm.Items()); actualLen != expectedLen {
+		t.Errorf("len(cm.Items()) after concurrent modifications returned %d while expected %d ", actualLen, expectedLen)
+	}
+}
+
+func copyItems(m map[interface{}]interface{}) map[interface{}]interface{} {
+	x := make(map[interface{}]interface{}, len(m))
+	for key, value := range m {
+		x[key] = value
+	}
+	return x
+}
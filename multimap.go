@@ -0,0 +1,179 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Represents how a MultiMap treats values of the same key.
+type MultiMapSemantics int
+
+// Semantics of MultiMap
+const (
+	// Values of a key form a list: the same value can be put several times and values are kept in order of Put.
+	MultiMapList MultiMapSemantics = iota
+	// Values of a key form a set: putting a value already present does nothing, values are kept in order of first Put.
+	MultiMapSet
+)
+
+// Returns short human-readable name of the semantics.
+func (this MultiMapSemantics) String() string {
+	switch this {
+	case MultiMapList:
+		return "list"
+	case MultiMapSet:
+		return "set"
+	}
+	return fmt.Sprintf("MultiMapSemantics(%d)", int(this))
+}
+
+// The MultiMap type represents concurrent-safe map associating every key with many values.
+//
+// Every method is atomic, so values can be added to and removed from a key concurrently without read-modify-write races.
+// Values are compared with ==, so RemoveValue, as well as Put with MultiMapSet semantics, panic on values of incomparable types.
+// The zero value is an empty map with MultiMapList semantics ready to use.
+type MultiMap struct {
+	semantics MultiMapSemantics
+	items     map[interface{}]*multiValues
+	lock      sync.RWMutex
+}
+
+// Holds values of a key.
+type multiValues struct {
+	values []interface{}
+	// Values present, for MultiMapSet semantics only
+	present map[interface{}]struct{}
+}
+
+// Instantiates and initializes empty MultiMap with given semantics of values of a key.
+func NewMultiMap(semantics MultiMapSemantics) *MultiMap {
+	return &MultiMap{semantics: semantics, items: map[interface{}]*multiValues{}}
+}
+
+// Returns semantics of values of a key.
+func (this *MultiMap) Semantics() MultiMapSemantics {
+	return this.semantics
+}
+
+// Adds the value to values of the key. Returns false if the value was not added, as it is already present with MultiMapSet semantics.
+func (this *MultiMap) Put(key interface{}, val interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.items == nil {
+		this.items = map[interface{}]*multiValues{}
+	}
+	mv, ok := this.items[key]
+	if !ok {
+		mv = &multiValues{}
+		if this.semantics == MultiMapSet {
+			mv.present = map[interface{}]struct{}{}
+		}
+	}
+	if mv.present != nil {
+		if _, ok := mv.present[val]; ok {
+			return false
+		}
+		mv.present[val] = struct{}{}
+	}
+	mv.values = append(mv.values, val)
+	this.items[key] = mv
+	return true
+}
+
+// Returns copy of values of the key in order, or an empty slice if there are none.
+func (this *MultiMap) GetAll(key interface{}) []interface{} {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	mv, ok := this.items[key]
+	if !ok {
+		return []interface{}{}
+	}
+	return append(make([]interface{}, 0, len(mv.values)), mv.values...)
+}
+
+// Removes the first occurrence of the value from values of the key. Returns false if the key had no such value.
+// The key is removed along with its last value.
+func (this *MultiMap) RemoveValue(key interface{}, val interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	mv, ok := this.items[key]
+	if !ok {
+		return false
+	}
+	for i, value := range mv.values {
+		if value == val {
+			copy(mv.values[i:], mv.values[i+1:])
+			mv.values[len(mv.values)-1] = nil
+			mv.values = mv.values[:len(mv.values)-1]
+			if mv.present != nil {
+				delete(mv.present, val)
+			}
+			if len(mv.values) == 0 {
+				delete(this.items, key)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the key with all its values and returns the removed values, or an empty slice if there were none.
+func (this *MultiMap) RemoveAll(key interface{}) []interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	mv, ok := this.items[key]
+	if !ok {
+		return []interface{}{}
+	}
+	delete(this.items, key)
+	return mv.values
+}
+
+// Returns number of values of the key.
+func (this *MultiMap) Count(key interface{}) int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	if mv, ok := this.items[key]; ok {
+		return len(mv.values)
+	}
+	return 0
+}
+
+// Returns number of keys having at least one value.
+func (this *MultiMap) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return len(this.items)
+}
+
+// Returns copy of content as non concurrent(general) map of keys to their values in order.
+func (this *MultiMap) Items() map[interface{}][]interface{} {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	x := make(map[interface{}][]interface{}, len(this.items))
+	for key, mv := range this.items {
+		x[key] = append(make([]interface{}, 0, len(mv.values)), mv.values...)
+	}
+	return x
+}
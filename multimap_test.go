@@ -0,0 +1,163 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestMultiMap(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		Semantics     MultiMapSemantics
+		ModifyFn      func(mm *MultiMap)
+		ExpectedItems map[interface{}][]interface{}
+	}{
+		{
+			TestAlias: "List keeps duplicates in order",
+			Semantics: MultiMapList,
+			ModifyFn: func(mm *MultiMap) {
+				mm.Put("topic1", "sub1")
+				mm.Put("topic1", "sub2")
+				mm.Put("topic1", "sub1")
+				mm.Put("topic2", "sub3")
+			},
+			ExpectedItems: map[interface{}][]interface{}{"topic1": {"sub1", "sub2", "sub1"}, "topic2": {"sub3"}},
+		},
+		{
+			TestAlias: "Set ignores duplicates",
+			Semantics: MultiMapSet,
+			ModifyFn: func(mm *MultiMap) {
+				mm.Put("topic1", "sub1")
+				mm.Put("topic1", "sub2")
+				mm.Put("topic1", "sub1")
+			},
+			ExpectedItems: map[interface{}][]interface{}{"topic1": {"sub1", "sub2"}},
+		},
+		{
+			TestAlias: "List removes first occurrence",
+			Semantics: MultiMapList,
+			ModifyFn: func(mm *MultiMap) {
+				mm.Put("topic1", "sub1")
+				mm.Put("topic1", "sub2")
+				mm.Put("topic1", "sub1")
+				mm.RemoveValue("topic1", "sub1")
+				mm.RemoveValue("topic1", "missing")
+				mm.RemoveValue("missing", "sub1")
+			},
+			ExpectedItems: map[interface{}][]interface{}{"topic1": {"sub2", "sub1"}},
+		},
+		{
+			TestAlias: "Set value can be put again after removal",
+			Semantics: MultiMapSet,
+			ModifyFn: func(mm *MultiMap) {
+				mm.Put("topic1", "sub1")
+				mm.Put("topic1", "sub2")
+				mm.RemoveValue("topic1", "sub1")
+				mm.Put("topic1", "sub1")
+			},
+			ExpectedItems: map[interface{}][]interface{}{"topic1": {"sub2", "sub1"}},
+		},
+		{
+			TestAlias: "Key is removed with its last value",
+			Semantics: MultiMapList,
+			ModifyFn: func(mm *MultiMap) {
+				mm.Put("topic1", "sub1")
+				mm.Put("topic2", "sub2")
+				mm.Put("topic2", "sub3")
+				mm.RemoveValue("topic1", "sub1")
+				mm.RemoveAll("topic2")
+			},
+			ExpectedItems: map[interface{}][]interface{}{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		semantics := testCase.Semantics
+		modifyFn := testCase.ModifyFn
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			mm := NewMultiMap(semantics)
+
+			modifyFn(mm)
+
+			if actualItems := mm.Items(); !reflect.DeepEqual(actualItems, expectedItems) {
+				t.Errorf("%s :: mm.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+			if actualLen := mm.Len(); actualLen != len(expectedItems) {
+				t.Errorf("%s :: mm.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedItems))
+			}
+			for key, expectedValues := range expectedItems {
+				if actualValues := mm.GetAll(key); !reflect.DeepEqual(actualValues, expectedValues) {
+					t.Errorf("%s :: mm.GetAll(%#v) returned %#v while expected %#v ", testAlias, key, actualValues, expectedValues)
+				}
+				if actualCount := mm.Count(key); actualCount != len(expectedValues) {
+					t.Errorf("%s :: mm.Count(%#v) returned %d while expected %d ", testAlias, key, actualCount, len(expectedValues))
+				}
+			}
+			if actualValues := mm.GetAll("missing"); len(actualValues) != 0 || actualValues == nil {
+				t.Errorf("%s :: mm.GetAll(\"missing\") returned %#v while expected empty slice ", testAlias, actualValues)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestMultiMapConcurrentPut(t *testing.T) {
+
+	mm := NewMultiMap(MultiMapSet)
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				mm.Put("topic", (g+1)*1000+i)
+				mm.Put("topic", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if actualCount := mm.Count("topic"); actualCount != 8*500+500 {
+		t.Errorf("mm.Count(\"topic\") returned %d while expected %d ", actualCount, 8*500+500)
+	}
+
+}
+
+func TestMultiMapZeroValue(t *testing.T) {
+
+	var mm MultiMap
+
+	if actual := mm.GetAll("topic"); len(actual) != 0 {
+		t.Errorf("mm.GetAll(\"topic\") returned %#v for zero value while expected no values ", actual)
+	}
+	mm.Put("topic", "subscriber")
+	mm.Put("topic", "subscriber")
+	if mm.Semantics() != MultiMapList || mm.Count("topic") != 2 {
+		t.Errorf("zero value has %v semantics and counts %d values while expected list semantics and 2 values ", mm.Semantics(), mm.Count("topic"))
+	}
+
+}
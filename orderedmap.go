@@ -0,0 +1,400 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// The OrderedMap type represents concurrent-safe map remembering the order keys were inserted in.
+//
+// Setting an existing key keeps its position, while MoveToFront and MoveToBack reorder keys explicitly.
+// Entries, Keys, Range and MarshalJSON follow the order, and UnmarshalJSON keeps the order of keys of the document.
+// SetMany and the other batch methods take slices of entries rather than Go maps, so new keys are appended in the given order.
+// Checked methods such as TrySet return ErrUnhashableKey. Conversion methods such as ToRecursiveMap are left to ConcurrentMap,
+// which can keep the order itself, see WithInsertionOrder. The zero value is an empty map ready to use.
+type OrderedMap struct {
+	// Entries in order, holding Entry values
+	entries list.List
+	// Elements of `entries` by key
+	elements map[interface{}]*list.Element
+	lock     sync.RWMutex
+}
+
+// Instantiates and initializes empty OrderedMap.
+func NewOrdered() *OrderedMap {
+	return &OrderedMap{elements: map[interface{}]*list.Element{}}
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *OrderedMap) Get(key interface{}) (interface{}, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	if element, ok := this.elements[key]; ok {
+		return element.Value.(Entry).Value, true
+	}
+	return nil, false
+}
+
+// Sets the given value under the specified key. A new key is appended to the back, an existing one keeps its position.
+func (this *OrderedMap) Set(key interface{}, val interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.set(key, val)
+}
+
+// Must be called under the write lock.
+func (this *OrderedMap) set(key interface{}, val interface{}) {
+	if this.elements == nil {
+		this.elements = map[interface{}]*list.Element{}
+	}
+	if element, ok := this.elements[key]; ok {
+		element.Value = Entry{Key: key, Value: val}
+		return
+	}
+	this.elements[key] = this.entries.PushBack(Entry{Key: key, Value: val})
+}
+
+// Appends the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with the same key.
+func (this *OrderedMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if _, ok := this.elements[key]; ok {
+		return false
+	}
+	this.set(key, val)
+	return true
+}
+
+// Removes an element from the map.
+func (this *OrderedMap) Remove(key interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if element, ok := this.elements[key]; ok {
+		this.entries.Remove(element)
+		delete(this.elements, key)
+	}
+}
+
+// Retrieves an element from map under given key, same as Get.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *OrderedMap) TryGet(key interface{}) (interface{}, bool, error) {
+	if err := checkHashable(key); err != nil {
+		return nil, false, err
+	}
+	val, ok := this.Get(key)
+	return val, ok, nil
+}
+
+// Sets the given value under the specified key, same as Set.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *OrderedMap) TrySet(key interface{}, val interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Set(key, val)
+	return nil
+}
+
+// Appends the given value under the specified key if the key didn't exist upon invokation, same as SetIfNotExists.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *OrderedMap) TrySetIfNotExists(key interface{}, val interface{}) (bool, error) {
+	if err := checkHashable(key); err != nil {
+		return false, err
+	}
+	return this.SetIfNotExists(key, val), nil
+}
+
+// Removes an element from the map, same as Remove.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be used as a map key.
+func (this *OrderedMap) TryRemove(key interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Remove(key)
+	return nil
+}
+
+// Sets all the given entries under a single lock acquisition. New keys are appended in the order of `entries`.
+func (this *OrderedMap) SetMany(entries []Entry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, entry := range entries {
+		this.set(entry.Key, entry.Value)
+	}
+}
+
+// Appends the given entries whose keys didn't exist upon invokation, under a single lock acquisition.
+// Returns keys of inserted entries; entries with already existing keys, including earlier ones of `entries`, are skipped.
+func (this *OrderedMap) SetManyIfNotExists(entries []Entry) []interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	inserted := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		if _, ok := this.elements[entry.Key]; !ok {
+			this.set(entry.Key, entry.Value)
+			inserted = append(inserted, entry.Key)
+		}
+	}
+	return inserted
+}
+
+// Retrieves elements under given keys under a single lock acquisition.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *OrderedMap) GetMany(keys []interface{}) ([]Entry, []interface{}) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	found := make([]Entry, 0, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if element, ok := this.elements[key]; ok {
+			found = append(found, element.Value.(Entry))
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys under a single lock acquisition.
+func (this *OrderedMap) RemoveMany(keys []interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, key := range keys {
+		if element, ok := this.elements[key]; ok {
+			this.entries.Remove(element)
+			delete(this.elements, key)
+		}
+	}
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`, which does not keep the order.
+func (this *OrderedMap) Items() map[interface{}]interface{} {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	x := make(map[interface{}]interface{}, len(this.elements))
+	for key, element := range this.elements {
+		x[key] = element.Value.(Entry).Value
+	}
+	return x
+}
+
+// Returns number of elements in the map.
+func (this *OrderedMap) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return len(this.elements)
+}
+
+// Returns copy of content as a slice of entries in order.
+func (this *OrderedMap) Entries() []Entry {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	x := make([]Entry, 0, len(this.elements))
+	for element := this.entries.Front(); element != nil; element = element.Next() {
+		x = append(x, element.Value.(Entry))
+	}
+	return x
+}
+
+// Returns keys in order.
+func (this *OrderedMap) Keys() []interface{} {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	x := make([]interface{}, 0, len(this.elements))
+	for element := this.entries.Front(); element != nil; element = element.Next() {
+		x = append(x, element.Value.(Entry).Key)
+	}
+	return x
+}
+
+// Calls `fn` for every element in order, until `fn` returns false.
+// It iterates over a snapshot, so `fn` may modify the map.
+func (this *OrderedMap) Range(fn func(key, value interface{}) bool) {
+	for _, entry := range this.Entries() {
+		if !fn(entry.Key, entry.Value) {
+			return
+		}
+	}
+}
+
+// Returns the first entry, or false if the map is empty.
+func (this *OrderedMap) First() (Entry, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	if element := this.entries.Front(); element != nil {
+		return element.Value.(Entry), true
+	}
+	return Entry{}, false
+}
+
+// Returns the last entry, or false if the map is empty.
+func (this *OrderedMap) Last() (Entry, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	if element := this.entries.Back(); element != nil {
+		return element.Value.(Entry), true
+	}
+	return Entry{}, false
+}
+
+// Moves the key to the front. Returns false in case there is no entry associated with the key.
+func (this *OrderedMap) MoveToFront(key interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	element, ok := this.elements[key]
+	if ok {
+		this.entries.MoveToFront(element)
+	}
+	return ok
+}
+
+// Moves the key to the back. Returns false in case there is no entry associated with the key.
+func (this *OrderedMap) MoveToBack(key interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	element, ok := this.elements[key]
+	if ok {
+		this.entries.MoveToBack(element)
+	}
+	return ok
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), rendering keys in order.
+//
+// Non-string keys are rendered with fmt.Sprint and nested maps are rendered as JSON objects, see ConcurrentMap.MarshalJSON.
+// Returns *CycleError if the map references itself, directly or through nested maps.
+func (this *OrderedMap) MarshalJSON() ([]byte, error) {
+	value, err := toJSONValue(cycleGuard{}, nil, this)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), keeping the order of keys of the document.
+//
+// Nested objects are unmarshalled into *OrderedMap, also inside arrays, and other values the same way as into interface{}.
+// While unmarshalling on non-empty map, overlapping key-values are overwritten in place and new keys are appended.
+func (this *OrderedMap) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		// By convention of json.Unmarshaler, null is a no-op
+		return nil
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("concurrentmap: cannot unmarshal %v into OrderedMap", token)
+	}
+	entries, err := decodeOrderedEntries(decoder, newOrderedObject)
+	if err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("concurrentmap: unexpected data after top-level object")
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, entry := range entries {
+		this.set(entry.Key, entry.Value)
+	}
+	return nil
+}
+
+// Decodes members of an object, whose opening delimiter has been consumed, up to and including the closing one.
+// Nested objects are built by `newObject` from their members in order.
+func decodeOrderedEntries(decoder *json.Decoder, newObject func(entries []Entry) interface{}) ([]Entry, error) {
+	entries := []Entry{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeOrderedValue(decoder, newObject)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Key: token.(string), Value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func decodeOrderedValue(decoder *json.Decoder, newObject func(entries []Entry) interface{}) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		entries, err := decodeOrderedEntries(decoder, newObject)
+		if err != nil {
+			return nil, err
+		}
+		return newObject(entries), nil
+	case json.Delim('['):
+		values := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrderedValue(decoder, newObject)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	return token, nil
+}
+
+func newOrderedObject(entries []Entry) interface{} {
+	om := NewOrdered()
+	for _, entry := range entries {
+		om.set(entry.Key, entry.Value)
+	}
+	return om
+}
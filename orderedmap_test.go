@@ -0,0 +1,259 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestOrderedMapOrder(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias     string
+		ModifyFn      func(om *OrderedMap)
+		ExpectedKeys  []interface{}
+		ExpectedFirst Entry
+		ExpectedLast  Entry
+	}{
+		{
+			TestAlias: "Insertion order",
+			ModifyFn: func(om *OrderedMap) {
+				om.Set("key3", 3)
+				om.Set("key1", 1)
+				om.SetIfNotExists("key2", 2)
+			},
+			ExpectedKeys:  []interface{}{"key3", "key1", "key2"},
+			ExpectedFirst: Entry{Key: "key3", Value: 3},
+			ExpectedLast:  Entry{Key: "key2", Value: 2},
+		},
+		{
+			TestAlias: "Overwrite keeps position",
+			ModifyFn: func(om *OrderedMap) {
+				om.Set("key1", 1)
+				om.Set("key2", 2)
+				om.Set("key1", 11)
+				om.SetIfNotExists("key2", 22)
+			},
+			ExpectedKeys:  []interface{}{"key1", "key2"},
+			ExpectedFirst: Entry{Key: "key1", Value: 11},
+			ExpectedLast:  Entry{Key: "key2", Value: 2},
+		},
+		{
+			TestAlias: "Remove and append again",
+			ModifyFn: func(om *OrderedMap) {
+				om.Set("key1", 1)
+				om.Set("key2", 2)
+				om.Remove("key1")
+				om.Set("key1", 1)
+			},
+			ExpectedKeys:  []interface{}{"key2", "key1"},
+			ExpectedFirst: Entry{Key: "key2", Value: 2},
+			ExpectedLast:  Entry{Key: "key1", Value: 1},
+		},
+		{
+			TestAlias: "Move to front and back",
+			ModifyFn: func(om *OrderedMap) {
+				om.Set("key1", 1)
+				om.Set("key2", 2)
+				om.Set("key3", 3)
+				om.MoveToFront("key3")
+				om.MoveToBack("key1")
+				om.MoveToBack("missingKey")
+			},
+			ExpectedKeys:  []interface{}{"key3", "key2", "key1"},
+			ExpectedFirst: Entry{Key: "key3", Value: 3},
+			ExpectedLast:  Entry{Key: "key1", Value: 1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		modifyFn := testCase.ModifyFn
+		expectedKeys := testCase.ExpectedKeys
+		expectedFirst := testCase.ExpectedFirst
+		expectedLast := testCase.ExpectedLast
+
+		testFn := func(t *testing.T) {
+
+			var om OrderedMap
+			modifyFn(&om)
+
+			if actualKeys := om.Keys(); !reflect.DeepEqual(actualKeys, expectedKeys) {
+				t.Errorf("%s :: om.Keys() returned %#v while expected %#v ", testAlias, actualKeys, expectedKeys)
+			}
+			rangedKeys := []interface{}{}
+			om.Range(func(key, value interface{}) bool {
+				rangedKeys = append(rangedKeys, key)
+				return true
+			})
+			if !reflect.DeepEqual(rangedKeys, expectedKeys) {
+				t.Errorf("%s :: om.Range(...) visited %#v while expected %#v ", testAlias, rangedKeys, expectedKeys)
+			}
+			if actualFirst, ok := om.First(); !ok || actualFirst != expectedFirst {
+				t.Errorf("%s :: om.First() returned %#v, %v while expected %#v, true ", testAlias, actualFirst, ok, expectedFirst)
+			}
+			if actualLast, ok := om.Last(); !ok || actualLast != expectedLast {
+				t.Errorf("%s :: om.Last() returned %#v, %v while expected %#v, true ", testAlias, actualLast, ok, expectedLast)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias    string
+		JSON         string
+		ExpectedJSON string
+		ExpectError  bool
+	}{
+		{
+			TestAlias:    "Empty object",
+			JSON:         `{}`,
+			ExpectedJSON: `{}`,
+		},
+		{
+			TestAlias:    "Key order is kept",
+			JSON:         `{"zeta": 1, "alpha": "a", "mid": null, "bool": true}`,
+			ExpectedJSON: `{"zeta":1,"alpha":"a","mid":null,"bool":true}`,
+		},
+		{
+			TestAlias:    "Nested objects and arrays",
+			JSON:         `{"z": {"b": 1, "a": [{"y": 1, "x": 2}, 3]}, "a": []}`,
+			ExpectedJSON: `{"z":{"b":1,"a":[{"y":1,"x":2},3]},"a":[]}`,
+		},
+		{
+			TestAlias:    "Null is a no-op",
+			JSON:         `null`,
+			ExpectedJSON: `{}`,
+		},
+		{
+			TestAlias:   "Not an object",
+			JSON:        `[1, 2]`,
+			ExpectError: true,
+		},
+		{
+			TestAlias:   "Malformed document",
+			JSON:        `{"a": 1`,
+			ExpectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		data := testCase.JSON
+		expectedJSON := testCase.ExpectedJSON
+		expectError := testCase.ExpectError
+
+		testFn := func(t *testing.T) {
+
+			om := NewOrdered()
+			err := json.Unmarshal([]byte(data), om)
+
+			if (err != nil) != expectError {
+				t.Fatalf("%s :: json.Unmarshal(...) returned error %v while expected error: %v ", testAlias, err, expectError)
+			}
+			if expectError {
+				return
+			}
+			actualJSON, err := json.Marshal(om)
+			if err != nil {
+				t.Errorf("%s :: json.Marshal(om) returned unexpected error %v ", testAlias, err)
+			}
+			if string(actualJSON) != expectedJSON {
+				t.Errorf("%s :: json.Marshal(om) returned \r\n %s \r\n while expected \r\n %s ", testAlias, actualJSON, expectedJSON)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestOrderedMapBatchAndTryMethods(t *testing.T) {
+
+	om := NewOrdered()
+	om.SetMany([]Entry{{Key: "zeta", Value: 1}, {Key: "alpha", Value: 2}})
+	inserted := om.SetManyIfNotExists([]Entry{{Key: "alpha", Value: 3}, {Key: "mid", Value: 4}, {Key: "mid", Value: 5}})
+	if !reflect.DeepEqual(inserted, []interface{}{"mid"}) {
+		t.Errorf("om.SetManyIfNotExists(...) returned %#v while expected [mid] ", inserted)
+	}
+	found, missing := om.GetMany([]interface{}{"mid", "zeta", "none"})
+	if expected := []Entry{{Key: "mid", Value: 4}, {Key: "zeta", Value: 1}}; !reflect.DeepEqual(found, expected) || !reflect.DeepEqual(missing, []interface{}{"none"}) {
+		t.Errorf("om.GetMany(...) returned %#v, %#v while expected %#v, [none] ", found, missing, expected)
+	}
+	om.RemoveMany([]interface{}{"zeta", "none"})
+	if actualKeys := om.Keys(); !reflect.DeepEqual(actualKeys, []interface{}{"alpha", "mid"}) {
+		t.Errorf("om.Keys() returned %#v while expected [alpha mid] ", actualKeys)
+	}
+
+	unhashable := []interface{}{"slice"}
+	if err := om.TrySet(unhashable, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("om.TrySet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, _, err := om.TryGet(unhashable); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("om.TryGet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, err := om.TrySetIfNotExists(unhashable, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("om.TrySetIfNotExists(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if err := om.TryRemove(unhashable); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("om.TryRemove(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+}
+
+func TestOrderedMapUnmarshalNested(t *testing.T) {
+
+	om := NewOrdered()
+	om.Set("keep", 1)
+	if err := om.UnmarshalJSON([]byte(`{"nested": {"b": 1, "a": 2}, "keep": 3}`)); err != nil {
+		t.Fatalf("om.UnmarshalJSON(...) returned unexpected error %v ", err)
+	}
+
+	if actualKeys := om.Keys(); !reflect.DeepEqual(actualKeys, []interface{}{"keep", "nested"}) {
+		t.Errorf("om.Keys() returned %#v ", actualKeys)
+	}
+	nested, _ := om.Get("nested")
+	nestedMap, ok := nested.(*OrderedMap)
+	if !ok {
+		t.Fatalf("om.Get(\"nested\") returned %T while expected *OrderedMap ", nested)
+	}
+	if actualKeys := nestedMap.Keys(); !reflect.DeepEqual(actualKeys, []interface{}{"b", "a"}) {
+		t.Errorf("nested.Keys() returned %#v ", actualKeys)
+	}
+
+}
+
+func TestOrderedMapMarshalCycle(t *testing.T) {
+
+	om := NewOrdered()
+	cm := New(0)
+	cm.Set("back", om)
+	om.Set("self", om)
+	om.Set("through", cm)
+
+	_, err := om.MarshalJSON()
+	var cycleError *CycleError
+	if !errors.As(err, &cycleError) {
+		t.Errorf("om.MarshalJSON() returned %v while expected *CycleError ", err)
+	}
+
+}
@@ -0,0 +1,269 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+// The PersistentMap type represents immutable map based on hash array mapped trie (HAMT).
+//
+// Modifications return new versions of the map, sharing all unchanged parts of the trie with the original,
+// so keeping many versions costs O(log n) memory per change instead of a full copy.
+// Since it is immutable, it is safe for concurrent use without any synchronization.
+// Keys must be of comparable types. The nil *PersistentMap is a valid empty map.
+type PersistentMap struct {
+	root *hamtNode
+	size int
+}
+
+// Represents a branching node: a bitmap of present branches and the array of *hamtLeaf, *hamtNode or *hamtCollision branches.
+type hamtNode struct {
+	bmp   uint32
+	array []interface{}
+}
+
+// Represents a key-value leaf.
+type hamtLeaf struct {
+	key   interface{}
+	hash  uint64
+	value interface{}
+}
+
+// Represents leaves whose keys have the same full hash.
+type hamtCollision struct {
+	hash   uint64
+	leaves []*hamtLeaf
+}
+
+// Returns an empty PersistentMap.
+func NewPersistent() *PersistentMap {
+	return &PersistentMap{}
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *PersistentMap) Get(key interface{}) (interface{}, bool) {
+	if this == nil || this.root == nil {
+		return nil, false
+	}
+	hash := ctrieHash(key)
+	node := this.root
+	for lev := uint(0); ; lev += ctrieLevelBits {
+		flag, pos := flagPos(hash, lev, node.bmp)
+		if node.bmp&flag == 0 {
+			return nil, false
+		}
+		switch branch := node.array[pos].(type) {
+		case *hamtNode:
+			node = branch
+		case *hamtLeaf:
+			if branch.hash == hash && branch.key == key {
+				return branch.value, true
+			}
+			return nil, false
+		case *hamtCollision:
+			for _, leaf := range branch.leaves {
+				if leaf.key == key {
+					return leaf.value, true
+				}
+			}
+			return nil, false
+		}
+	}
+}
+
+// Returns a new version of the map with the given value set under the specified key.
+func (this *PersistentMap) With(key interface{}, val interface{}) *PersistentMap {
+	leaf := &hamtLeaf{key: key, hash: ctrieHash(key), value: val}
+	if this == nil || this.root == nil {
+		this = &PersistentMap{root: &hamtNode{}}
+	}
+	root, added := this.root.with(leaf, 0)
+	size := this.size
+	if added {
+		size++
+	}
+	return &PersistentMap{root: root, size: size}
+}
+
+// Returns a new version of the map without the specified key, or the map itself if there is no such key.
+func (this *PersistentMap) Without(key interface{}) *PersistentMap {
+	if this == nil || this.root == nil {
+		return this
+	}
+	root, removed := this.root.without(key, ctrieHash(key), 0)
+	if !removed {
+		return this
+	}
+	return &PersistentMap{root: root, size: this.size - 1}
+}
+
+// Returns number of elements in the map.
+func (this *PersistentMap) Len() int {
+	if this == nil {
+		return 0
+	}
+	return this.size
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+func (this *PersistentMap) Items() map[interface{}]interface{} {
+	x := make(map[interface{}]interface{}, this.Len())
+	this.Range(func(key, value interface{}) bool {
+		x[key] = value
+		return true
+	})
+	return x
+}
+
+// Calls `fn` for every element of the map, until `fn` returns false.
+func (this *PersistentMap) Range(fn func(key, value interface{}) bool) {
+	if this != nil && this.root != nil {
+		this.root.traverse(fn)
+	}
+}
+
+// Returns a copy of the node holding the `leaf`, and true if its key was not present.
+func (this *hamtNode) with(leaf *hamtLeaf, lev uint) (*hamtNode, bool) {
+	flag, pos := flagPos(leaf.hash, lev, this.bmp)
+	if this.bmp&flag == 0 {
+		array := make([]interface{}, len(this.array)+1)
+		copy(array, this.array[:pos])
+		array[pos] = leaf
+		copy(array[pos+1:], this.array[pos:])
+		return &hamtNode{bmp: this.bmp | flag, array: array}, true
+	}
+
+	var branch interface{}
+	added := true
+	switch x := this.array[pos].(type) {
+	case *hamtNode:
+		branch, added = x.with(leaf, lev+ctrieLevelBits)
+	case *hamtLeaf:
+		if x.hash == leaf.hash && x.key == leaf.key {
+			branch, added = leaf, false
+		} else {
+			branch = hamtDual(x, leaf, lev+ctrieLevelBits)
+		}
+	case *hamtCollision:
+		leaves := make([]*hamtLeaf, 0, len(x.leaves)+1)
+		for _, l := range x.leaves {
+			if l.key == leaf.key {
+				added = false
+				continue
+			}
+			leaves = append(leaves, l)
+		}
+		branch = &hamtCollision{hash: x.hash, leaves: append(leaves, leaf)}
+	}
+	array := make([]interface{}, len(this.array))
+	copy(array, this.array)
+	array[pos] = branch
+	return &hamtNode{bmp: this.bmp, array: array}, added
+}
+
+// Returns a copy of the node without the `key`, or nil if the copy is empty, and true if the key was present.
+func (this *hamtNode) without(key interface{}, hash uint64, lev uint) (*hamtNode, bool) {
+	flag, pos := flagPos(hash, lev, this.bmp)
+	if this.bmp&flag == 0 {
+		return this, false
+	}
+
+	var branch interface{}
+	switch x := this.array[pos].(type) {
+	case *hamtNode:
+		child, removed := x.without(key, hash, lev+ctrieLevelBits)
+		if !removed {
+			return this, false
+		}
+		if child != nil {
+			branch = child
+			if len(child.array) == 1 {
+				if _, ok := child.array[0].(*hamtNode); !ok {
+					// Pull a single leaf or collision up, so that removals shrink the trie
+					branch = child.array[0]
+				}
+			}
+		}
+	case *hamtLeaf:
+		if x.hash != hash || x.key != key {
+			return this, false
+		}
+	case *hamtCollision:
+		leaves := make([]*hamtLeaf, 0, len(x.leaves))
+		for _, l := range x.leaves {
+			if l.key != key {
+				leaves = append(leaves, l)
+			}
+		}
+		if len(leaves) == len(x.leaves) {
+			return this, false
+		}
+		branch = &hamtCollision{hash: x.hash, leaves: leaves}
+		if len(leaves) == 1 {
+			branch = leaves[0]
+		}
+	}
+
+	if branch != nil {
+		array := make([]interface{}, len(this.array))
+		copy(array, this.array)
+		array[pos] = branch
+		return &hamtNode{bmp: this.bmp, array: array}, true
+	}
+	if len(this.array) == 1 {
+		return nil, true
+	}
+	array := make([]interface{}, len(this.array)-1)
+	copy(array, this.array[:pos])
+	copy(array[pos:], this.array[pos+1:])
+	return &hamtNode{bmp: this.bmp ^ flag, array: array}, true
+}
+
+func (this *hamtNode) traverse(fn func(key, value interface{}) bool) bool {
+	for _, branch := range this.array {
+		switch x := branch.(type) {
+		case *hamtNode:
+			if !x.traverse(fn) {
+				return false
+			}
+		case *hamtLeaf:
+			if !fn(x.key, x.value) {
+				return false
+			}
+		case *hamtCollision:
+			for _, leaf := range x.leaves {
+				if !fn(leaf.key, leaf.value) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Returns the branch holding two leaves with different keys, branching at the level `lev` or deeper.
+func hamtDual(x, y *hamtLeaf, lev uint) interface{} {
+	if lev >= ctrieHashBits {
+		return &hamtCollision{hash: x.hash, leaves: []*hamtLeaf{x, y}}
+	}
+	xIdx, yIdx := (x.hash>>lev)&ctrieLevelMask, (y.hash>>lev)&ctrieLevelMask
+	bmp := uint32(1)<<xIdx | uint32(1)<<yIdx
+	switch {
+	case xIdx == yIdx:
+		return &hamtNode{bmp: bmp, array: []interface{}{hamtDual(x, y, lev+ctrieLevelBits)}}
+	case xIdx < yIdx:
+		return &hamtNode{bmp: bmp, array: []interface{}{x, y}}
+	default:
+		return &hamtNode{bmp: bmp, array: []interface{}{y, x}}
+	}
+}
@@ -0,0 +1,211 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestPersistentMapVersions(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias             string
+		Items                 map[interface{}]interface{}
+		ModifyFn              func(pm *PersistentMap) *PersistentMap
+		ExpectedOriginalItems map[interface{}]interface{}
+		ExpectedModifiedItems map[interface{}]interface{}
+	}{
+		{
+			TestAlias:             "With on nil map",
+			Items:                 nil,
+			ModifyFn:              func(pm *PersistentMap) *PersistentMap { return pm.With("key1", "stringValue") },
+			ExpectedOriginalItems: map[interface{}]interface{}{},
+			ExpectedModifiedItems: map[interface{}]interface{}{"key1": "stringValue"},
+		},
+		{
+			TestAlias:             "With new key",
+			Items:                 map[interface{}]interface{}{"key1": "stringValue"},
+			ModifyFn:              func(pm *PersistentMap) *PersistentMap { return pm.With("key2", 123) },
+			ExpectedOriginalItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedModifiedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+		},
+		{
+			TestAlias:             "With existing key",
+			Items:                 map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			ModifyFn:              func(pm *PersistentMap) *PersistentMap { return pm.With("key2", 321) },
+			ExpectedOriginalItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			ExpectedModifiedItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 321},
+		},
+		{
+			TestAlias:             "Without existing key",
+			Items:                 map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			ModifyFn:              func(pm *PersistentMap) *PersistentMap { return pm.Without("key1") },
+			ExpectedOriginalItems: map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			ExpectedModifiedItems: map[interface{}]interface{}{"key2": 123},
+		},
+		{
+			TestAlias:             "Without missing key",
+			Items:                 map[interface{}]interface{}{"key1": "stringValue"},
+			ModifyFn:              func(pm *PersistentMap) *PersistentMap { return pm.Without("missingKey") },
+			ExpectedOriginalItems: map[interface{}]interface{}{"key1": "stringValue"},
+			ExpectedModifiedItems: map[interface{}]interface{}{"key1": "stringValue"},
+		},
+		{
+			TestAlias: "Without all keys of a large map",
+			Items:     intItems(0, 1000),
+			ModifyFn: func(pm *PersistentMap) *PersistentMap {
+				for i := 0; i < 1000; i++ {
+					pm = pm.Without(i)
+				}
+				return pm
+			},
+			ExpectedOriginalItems: intItems(0, 1000),
+			ExpectedModifiedItems: map[interface{}]interface{}{},
+		},
+		{
+			TestAlias: "Many versions of a large map",
+			Items:     intItems(0, 1000),
+			ModifyFn: func(pm *PersistentMap) *PersistentMap {
+				for i := 500; i < 1500; i++ {
+					pm = pm.With(i, i)
+				}
+				for i := 0; i < 500; i++ {
+					pm = pm.Without(i)
+				}
+				return pm
+			},
+			ExpectedOriginalItems: intItems(0, 1000),
+			ExpectedModifiedItems: intItems(500, 1500),
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		items := testCase.Items
+		modifyFn := testCase.ModifyFn
+		expectedOriginalItems := testCase.ExpectedOriginalItems
+		expectedModifiedItems := testCase.ExpectedModifiedItems
+
+		testFn := func(t *testing.T) {
+
+			var original *PersistentMap
+			if items != nil {
+				original = NewVersioned(items).Snapshot()
+			}
+
+			modified := modifyFn(original)
+
+			if actualItems := original.Items(); !reflect.DeepEqual(actualItems, expectedOriginalItems) {
+				t.Errorf("%s :: original.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedOriginalItems)
+			}
+			if actualLen := original.Len(); actualLen != len(expectedOriginalItems) {
+				t.Errorf("%s :: original.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedOriginalItems))
+			}
+			if actualItems := modified.Items(); !reflect.DeepEqual(actualItems, expectedModifiedItems) {
+				t.Errorf("%s :: modified.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedModifiedItems)
+			}
+			if actualLen := modified.Len(); actualLen != len(expectedModifiedItems) {
+				t.Errorf("%s :: modified.Len() returned %d while expected %d ", testAlias, actualLen, len(expectedModifiedItems))
+			}
+			for key, expectedValue := range expectedModifiedItems {
+				if actualValue, ok := modified.Get(key); !ok || actualValue != expectedValue {
+					t.Errorf("%s :: modified.Get(%v) returned (%v, %v) while expected (%v, true) ", testAlias, key, actualValue, ok, expectedValue)
+				}
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestVersionedMapSnapshot(t *testing.T) {
+
+	vm := NewVersioned(map[interface{}]interface{}{"key1": "stringValue"})
+	snapshot := vm.Snapshot()
+
+	vm.Set("key2", 123)
+	vm.Remove("key1")
+
+	expectedSnapshotItems := map[interface{}]interface{}{"key1": "stringValue"}
+	if actualItems := snapshot.Items(); !reflect.DeepEqual(actualItems, expectedSnapshotItems) {
+		t.Errorf("snapshot.Items() returned \r\n %#v \r\n while expected \r\n %#v ", actualItems, expectedSnapshotItems)
+	}
+	expectedItems := map[interface{}]interface{}{"key2": 123}
+	if actualItems := vm.Items(); !reflect.DeepEqual(actualItems, expectedItems) {
+		t.Errorf("vm.Items() returned \r\n %#v \r\n while expected \r\n %#v ", actualItems, expectedItems)
+	}
+
+	var zero VersionedMap
+	if actualLen := zero.Snapshot().Len(); actualLen != 0 {
+		t.Errorf("zero.Snapshot().Len() returned %d while expected 0 ", actualLen)
+	}
+
+}
+
+func intItems(from, to int) map[interface{}]interface{} {
+	items := make(map[interface{}]interface{}, to-from)
+	for i := from; i < to; i++ {
+		items[i] = i
+	}
+	return items
+}
+
+func TestVersionedMapBatchPublishesSingleVersion(t *testing.T) {
+
+	vm := NewVersioned(intItems(0, 10))
+	before := vm.Snapshot()
+
+	if inserted := vm.SetManyIfNotExists(intItems(5, 15)); len(inserted) != 5 {
+		t.Errorf("vm.SetManyIfNotExists(...) inserted %d keys while expected 5 ", len(inserted))
+	}
+	vm.RemoveMany([]interface{}{0, 14})
+	found, missing := vm.GetMany([]interface{}{1, 14})
+	if !reflect.DeepEqual(found, map[interface{}]interface{}{1: 1}) || !reflect.DeepEqual(missing, []interface{}{14}) {
+		t.Errorf("vm.GetMany(...) returned %#v, %#v while expected {1: 1}, [14] ", found, missing)
+	}
+	if actualItems := vm.Items(); !reflect.DeepEqual(actualItems, intItems(1, 14)) {
+		t.Errorf("vm.Items() returned %d elements while expected 13 ", len(actualItems))
+	}
+	if actualItems := before.Items(); !reflect.DeepEqual(actualItems, intItems(0, 10)) {
+		t.Errorf("before.Items() returned %d elements while expected the snapshot untouched ", len(actualItems))
+	}
+
+	if err := vm.TrySet([]interface{}{"slice"}, 1); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("vm.TrySet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+	if _, _, err := vm.TryGet([]interface{}{"slice"}); !errors.Is(err, ErrUnhashableKey) {
+		t.Errorf("vm.TryGet(unhashable) returned %v while expected ErrUnhashableKey ", err)
+	}
+}
+
+func TestVersionedMapJSON(t *testing.T) {
+
+	var vm VersionedMap
+	if err := json.Unmarshal([]byte(`{"route": {"upstream": "b"}, "version": 2}`), &vm); err != nil {
+		t.Fatalf("json.Unmarshal(...) returned unexpected error %v ", err)
+	}
+	actualJSON, err := json.Marshal(&vm)
+	if err != nil {
+		t.Fatalf("json.Marshal(vm) returned unexpected error %v ", err)
+	}
+	if expected := `{"route":{"upstream":"b"},"version":2}`; string(actualJSON) != expected {
+		t.Errorf("json.Marshal(vm) returned %s while expected %s ", actualJSON, expected)
+	}
+}
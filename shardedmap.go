@@ -0,0 +1,157 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "hash/maphash"
+
+// Default values of ShardedMap
+const (
+	// Represents number of shards used by NewSharded when non-positive count is given
+	DEFAULT_SHARDCOUNT = 32
+)
+
+// The ShardedMap type represents Map split into a number of independently locked ConcurrentMap shards.
+//
+// Keys are distributed among shards by their hash, so operations on different keys rarely contend for the same lock.
+// Items and batch methods lock one shard at a time, so unlike ConcurrentMap they do not provide a consistent snapshot
+// across shards. The zero value is not usable, use NewSharded.
+type ShardedMap struct {
+	shards []ConcurrentMap
+}
+
+// Instantiates and initializes ShardedMap with `shardCount` shards, rounded up to a power of two,
+// and total capacity of `initCap`.
+func NewSharded(shardCount int, initCap int) *ShardedMap {
+	if shardCount <= 0 {
+		shardCount = DEFAULT_SHARDCOUNT
+	}
+	n := 1
+	for n < shardCount {
+		n *= 2
+	}
+	shards := make([]ConcurrentMap, n)
+	for i := range shards {
+		shards[i].items = make(map[interface{}]interface{}, initCap/n)
+	}
+	return &ShardedMap{shards: shards}
+}
+
+// Returns the shard holding the `key`. Panics if the key is unhashable.
+func (this *ShardedMap) shard(key interface{}) *ConcurrentMap {
+	return &this.shards[maphash.Comparable(hashSeed, key)&uint64(len(this.shards)-1)]
+}
+
+// Groups the `keys` by shard index.
+func (this *ShardedMap) groupKeys(keys []interface{}) map[int][]interface{} {
+	groups := make(map[int][]interface{})
+	for _, key := range keys {
+		i := int(maphash.Comparable(hashSeed, key) & uint64(len(this.shards)-1))
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// Groups the `entries` by shard index.
+func (this *ShardedMap) groupEntries(entries map[interface{}]interface{}) map[int]map[interface{}]interface{} {
+	groups := make(map[int]map[interface{}]interface{})
+	for key, val := range entries {
+		i := int(maphash.Comparable(hashSeed, key) & uint64(len(this.shards)-1))
+		if groups[i] == nil {
+			groups[i] = make(map[interface{}]interface{})
+		}
+		groups[i][key] = val
+	}
+	return groups
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *ShardedMap) Get(key interface{}) (interface{}, bool) {
+	return this.shard(key).Get(key)
+}
+
+// Sets the given value under the specified key.
+func (this *ShardedMap) Set(key interface{}, val interface{}) {
+	this.shard(key).Set(key, val)
+}
+
+// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with the same key.
+func (this *ShardedMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	return this.shard(key).SetIfNotExists(key, val)
+}
+
+// Removes an element from the map.
+func (this *ShardedMap) Remove(key interface{}) {
+	this.shard(key).Remove(key)
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+// Each shard is copied consistently, but shards are copied one after another.
+func (this *ShardedMap) Items() map[interface{}]interface{} {
+	x := make(map[interface{}]interface{})
+	for i := range this.shards {
+		shard := &this.shards[i]
+		shard.lock.RLock()
+		for key, value := range shard.items {
+			x[key] = value
+		}
+		shard.lock.RUnlock()
+	}
+	return x
+}
+
+// Sets all the given entries with a single lock acquisition per shard.
+func (this *ShardedMap) SetMany(entries map[interface{}]interface{}) {
+	for i, group := range this.groupEntries(entries) {
+		this.shards[i].SetMany(group)
+	}
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, with a single lock acquisition per shard.
+// Returns keys of inserted entries; entries with already existing keys are left untouched.
+func (this *ShardedMap) SetManyIfNotExists(entries map[interface{}]interface{}) []interface{} {
+	inserted := make([]interface{}, 0, len(entries))
+	for i, group := range this.groupEntries(entries) {
+		inserted = append(inserted, this.shards[i].SetManyIfNotExists(group)...)
+	}
+	return inserted
+}
+
+// Retrieves elements under given keys with a single lock acquisition per shard.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *ShardedMap) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	found := make(map[interface{}]interface{}, len(keys))
+	for i, group := range this.groupKeys(keys) {
+		shardFound, _ := this.shards[i].GetMany(group)
+		for key, val := range shardFound {
+			found[key] = val
+		}
+	}
+	missing := []interface{}{}
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys with a single lock acquisition per shard.
+func (this *ShardedMap) RemoveMany(keys []interface{}) {
+	for i, group := range this.groupKeys(keys) {
+		this.shards[i].RemoveMany(group)
+	}
+}
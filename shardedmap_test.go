@@ -0,0 +1,94 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func TestShardedMapBatchCycle(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias        string
+		ShardCount       int
+		Items            map[interface{}]interface{}
+		Entries          map[interface{}]interface{}
+		ExpectedInserted []interface{}
+		RemoveKeys       []interface{}
+		ExpectedItems    map[interface{}]interface{}
+	}{
+		{
+			TestAlias:        "Single shard",
+			ShardCount:       1,
+			Items:            map[interface{}]interface{}{"key1": "stringValue", "key2": 123},
+			Entries:          map[interface{}]interface{}{"key2": 321, "key3": 4.56},
+			ExpectedInserted: []interface{}{"key3"},
+			RemoveKeys:       []interface{}{"key1", "key4"},
+			ExpectedItems:    map[interface{}]interface{}{"key2": 123, "key3": 4.56},
+		},
+		{
+			TestAlias:        "Default number of shards",
+			ShardCount:       0,
+			Items:            map[interface{}]interface{}{"key1": "stringValue", "key2": 123, "key5": true},
+			Entries:          map[interface{}]interface{}{"key2": 321, "key3": 4.56, "key4": nil},
+			ExpectedInserted: []interface{}{"key3", "key4"},
+			RemoveKeys:       []interface{}{"key1", "key4"},
+			ExpectedItems:    map[interface{}]interface{}{"key2": 123, "key3": 4.56, "key5": true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		cm := NewSharded(testCase.ShardCount, 0)
+		items := testCase.Items
+		entries := testCase.Entries
+		expectedInserted := testCase.ExpectedInserted
+		removeKeys := testCase.RemoveKeys
+		expectedItems := testCase.ExpectedItems
+
+		testFn := func(t *testing.T) {
+
+			cm.SetMany(items)
+
+			actualInserted := cm.SetManyIfNotExists(entries)
+			sort.Slice(actualInserted, func(i, j int) bool { return actualInserted[i].(string) < actualInserted[j].(string) })
+
+			if !(reflect.DeepEqual(actualInserted, expectedInserted)) {
+				t.Errorf("%s :: cm.SetManyIfNotExists(%#v) returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, entries, actualInserted, expectedInserted)
+			}
+
+			cm.RemoveMany(removeKeys)
+
+			keys := []interface{}{"missingKey"}
+			for key := range expectedItems {
+				keys = append(keys, key)
+			}
+			actualFound, actualMissing := cm.GetMany(keys)
+
+			if !(reflect.DeepEqual(actualFound, expectedItems)) || !(reflect.DeepEqual(actualMissing, []interface{}{"missingKey"})) {
+				t.Errorf("%s :: cm.GetMany(%#v) returned \r\n %#v, %#v \r\n while expected \r\n %#v, [missingKey] ", testAlias, keys, actualFound, actualMissing, expectedItems)
+			}
+			if actualItems := cm.Items(); !(reflect.DeepEqual(actualItems, expectedItems)) {
+				t.Errorf("%s :: cm.Items() returned \r\n %#v \r\n while expected \r\n %#v ", testAlias, actualItems, expectedItems)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
@@ -0,0 +1,490 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+)
+
+// Internal values of SortedMap
+const (
+	// Represents maximum number of levels of the skip list, enough for 2^32 elements
+	skipListMaxLevel = 16
+	// Represents number of elements Ascend and Descend collect under a single lock acquisition
+	skipListRangeChunk = 64
+)
+
+// The SortedMap type represents concurrent-safe map keeping keys sorted by a user-supplied comparator.
+//
+// It is a skip list under a RWMutex, so lookups and modifications cost O(log n) and range scans visit only keys in range.
+// Methods taking bounds treat nil as unbounded, so nil cannot be used as a bound itself.
+// Batch methods apply all their keys under one lock acquisition and MarshalJSON renders keys in sorted order.
+// Keys are checked by the comparator alone, so TrySet and the other checked methods report keys it panics on with ErrKeyType.
+// The zero value is not usable, as there is no comparator, use NewSorted.
+type SortedMap struct {
+	compare func(a, b interface{}) int
+	// Sentinel node, whose `next` are the first nodes of every level
+	head  skipNode
+	level int
+	size  int
+	lock  sync.RWMutex
+}
+
+type skipNode struct {
+	key, value interface{}
+	next       []*skipNode
+	// Previous node of the bottom level, or nil for the first one
+	prev *skipNode
+}
+
+// Instantiates empty SortedMap ordering keys by `compare`, which returns a negative number, zero or a positive number
+// when `a` is less than, equal to or greater than `b`. It must be a strict weak order and keys comparing equal are the same key.
+func NewSorted(compare func(a, b interface{}) int) *SortedMap {
+	return &SortedMap{compare: compare, head: skipNode{next: make([]*skipNode, skipListMaxLevel)}, level: 1}
+}
+
+// Returns the last node of every level with a key less than `key`, filling `update` if not nil.
+// Returns the bottom level one.
+func (this *SortedMap) findLess(key interface{}, update []*skipNode) *skipNode {
+	node := &this.head
+	for lev := this.level - 1; lev >= 0; lev-- {
+		for next := node.next[lev]; next != nil && this.compare(next.key, key) < 0; next = node.next[lev] {
+			node = next
+		}
+		if update != nil {
+			update[lev] = node
+		}
+	}
+	return node
+}
+
+// Returns the node holding `key` or nil.
+func (this *SortedMap) find(key interface{}) *skipNode {
+	if next := this.findLess(key, nil).next[0]; next != nil && this.compare(next.key, key) == 0 {
+		return next
+	}
+	return nil
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *SortedMap) Get(key interface{}) (interface{}, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	if node := this.find(key); node != nil {
+		return node.value, true
+	}
+	return nil, false
+}
+
+// Sets the given value under the specified key.
+func (this *SortedMap) Set(key interface{}, val interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.insert(key, val, false)
+}
+
+// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with the same key.
+func (this *SortedMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.insert(key, val, true)
+}
+
+func (this *SortedMap) insert(key interface{}, val interface{}, onlyIfAbsent bool) bool {
+	update := make([]*skipNode, skipListMaxLevel)
+	less := this.findLess(key, update)
+	if next := less.next[0]; next != nil && this.compare(next.key, key) == 0 {
+		if onlyIfAbsent {
+			return false
+		}
+		next.value = val
+		return true
+	}
+
+	level := randomSkipLevel()
+	for ; this.level < level; this.level++ {
+		update[this.level] = &this.head
+	}
+	node := &skipNode{key: key, value: val, next: make([]*skipNode, level)}
+	for lev := 0; lev < level; lev++ {
+		node.next[lev] = update[lev].next[lev]
+		update[lev].next[lev] = node
+	}
+	if less != &this.head {
+		node.prev = less
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node
+	}
+	this.size++
+	return true
+}
+
+// Returns level of a new node: every next level is taken with probability of 1/4.
+func randomSkipLevel() int {
+	level := 1 + bits.TrailingZeros64(rand.Uint64()|1<<62)/2
+	if level > skipListMaxLevel {
+		level = skipListMaxLevel
+	}
+	return level
+}
+
+// Removes an element from the map.
+func (this *SortedMap) Remove(key interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.remove(key)
+}
+
+// Must be called under the write lock.
+func (this *SortedMap) remove(key interface{}) {
+	update := make([]*skipNode, skipListMaxLevel)
+	node := this.findLess(key, update).next[0]
+	if node == nil || this.compare(node.key, key) != 0 {
+		return
+	}
+	for lev := 0; lev < len(node.next); lev++ {
+		update[lev].next[lev] = node.next[lev]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node.prev
+	}
+	for this.level > 1 && this.head.next[this.level-1] == nil {
+		this.level--
+	}
+	this.size--
+}
+
+// Returns ErrKeyType if the comparator panics comparing the `key` with itself, before the map gets locked.
+func (this *SortedMap) checkKey(key interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %T rejected by the comparator: %v", ErrKeyType, key, r)
+		}
+	}()
+	this.compare(key, key)
+	return nil
+}
+
+// Retrieves an element from map under given key, same as Get.
+// Returns ErrKeyType instead of panicking if the comparator rejects the key.
+func (this *SortedMap) TryGet(key interface{}) (interface{}, bool, error) {
+	if err := this.checkKey(key); err != nil {
+		return nil, false, err
+	}
+	val, ok := this.Get(key)
+	return val, ok, nil
+}
+
+// Sets the given value under the specified key, same as Set.
+// Returns ErrKeyType instead of panicking if the comparator rejects the key.
+func (this *SortedMap) TrySet(key interface{}, val interface{}) error {
+	if err := this.checkKey(key); err != nil {
+		return err
+	}
+	this.Set(key, val)
+	return nil
+}
+
+// Sets the given value under the specified key if the key didn't exist upon invokation, same as SetIfNotExists.
+// Returns ErrKeyType instead of panicking if the comparator rejects the key.
+func (this *SortedMap) TrySetIfNotExists(key interface{}, val interface{}) (bool, error) {
+	if err := this.checkKey(key); err != nil {
+		return false, err
+	}
+	return this.SetIfNotExists(key, val), nil
+}
+
+// Removes an element from the map, same as Remove.
+// Returns ErrKeyType instead of panicking if the comparator rejects the key.
+func (this *SortedMap) TryRemove(key interface{}) error {
+	if err := this.checkKey(key); err != nil {
+		return err
+	}
+	this.Remove(key)
+	return nil
+}
+
+// Sets all the given entries under a single lock acquisition.
+func (this *SortedMap) SetMany(entries map[interface{}]interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for key, val := range entries {
+		this.insert(key, val, false)
+	}
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, under a single lock acquisition.
+// Returns keys of inserted entries; entries with already existing keys are left untouched.
+func (this *SortedMap) SetManyIfNotExists(entries map[interface{}]interface{}) []interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	inserted := make([]interface{}, 0, len(entries))
+	for key, val := range entries {
+		if this.insert(key, val, true) {
+			inserted = append(inserted, key)
+		}
+	}
+	return inserted
+}
+
+// Retrieves elements under given keys under a single lock acquisition.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *SortedMap) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	found := make(map[interface{}]interface{}, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if node := this.find(key); node != nil {
+			found[key] = node.value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys under a single lock acquisition.
+func (this *SortedMap) RemoveMany(keys []interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, key := range keys {
+		this.remove(key)
+	}
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), rendering keys in sorted order.
+// Non-string keys are rendered with fmt.Sprint and nested maps as JSON objects, see ConcurrentMap.MarshalJSON.
+func (this *SortedMap) MarshalJSON() ([]byte, error) {
+	return marshalJSONEntries(this)
+}
+
+func (this *SortedMap) jsonEntries() []Entry {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	entries := make([]Entry, 0, this.size)
+	for node := this.head.next[0]; node != nil; node = node.next[0] {
+		entries = append(entries, Entry{Key: node.key, Value: node.value})
+	}
+	return entries
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), see ConcurrentMap.UnmarshalJSON.
+// Keys are strings, so the comparator must accept them; otherwise ErrKeyType is returned and the map is left untouched.
+func (this *SortedMap) UnmarshalJSON(data []byte) error {
+	entries, err := decodeJSONEntries(data)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := this.checkKey(entry.Key); err != nil {
+			return err
+		}
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, entry := range entries {
+		this.insert(entry.Key, entry.Value, false)
+	}
+	return nil
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`, which does not keep the order.
+func (this *SortedMap) Items() map[interface{}]interface{} {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	x := make(map[interface{}]interface{}, this.size)
+	for node := this.head.next[0]; node != nil; node = node.next[0] {
+		x[node.key] = node.value
+	}
+	return x
+}
+
+// Returns number of elements in the map.
+func (this *SortedMap) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return this.size
+}
+
+// Calls `fn` for elements with keys greater than or equal to `from` and less than `to` in ascending order, until `fn` returns false.
+//
+// Elements are collected in chunks under the read lock and `fn` is called with no lock held, so it may call any method
+// of the map. Each chunk is a consistent snapshot and the next one continues after its last key, so keys present for
+// the whole iteration are visited exactly once, while keys set or removed meanwhile may or may not be.
+func (this *SortedMap) Ascend(from, to interface{}, fn func(key, value interface{}) bool) {
+	chunk := make([]Entry, 0, skipListRangeChunk)
+	for inclusive := true; ; inclusive = false {
+		chunk = this.ascendChunk(from, inclusive, to, chunk[:0])
+		for _, entry := range chunk {
+			if !fn(entry.Key, entry.Value) {
+				return
+			}
+		}
+		if len(chunk) < cap(chunk) {
+			return
+		}
+		from = chunk[len(chunk)-1].Key
+	}
+}
+
+// Appends to `chunk` up to its capacity elements of the range in ascending order.
+func (this *SortedMap) ascendChunk(from interface{}, inclusive bool, to interface{}, chunk []Entry) []Entry {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	node := this.head.next[0]
+	if from != nil {
+		node = this.findLess(from, nil).next[0]
+		if !inclusive && node != nil && this.compare(node.key, from) == 0 {
+			node = node.next[0]
+		}
+	}
+	for ; node != nil && len(chunk) < cap(chunk); node = node.next[0] {
+		if to != nil && this.compare(node.key, to) >= 0 {
+			break
+		}
+		chunk = append(chunk, Entry{Key: node.key, Value: node.value})
+	}
+	return chunk
+}
+
+// Calls `fn` for elements with keys less than or equal to `from` and greater than `to` in descending order, until `fn` returns false.
+//
+// Elements are collected in chunks the same way as by Ascend, so `fn` may call any method of the map.
+func (this *SortedMap) Descend(from, to interface{}, fn func(key, value interface{}) bool) {
+	chunk := make([]Entry, 0, skipListRangeChunk)
+	for inclusive := true; ; inclusive = false {
+		chunk = this.descendChunk(from, inclusive, to, chunk[:0])
+		for _, entry := range chunk {
+			if !fn(entry.Key, entry.Value) {
+				return
+			}
+		}
+		if len(chunk) < cap(chunk) {
+			return
+		}
+		from = chunk[len(chunk)-1].Key
+	}
+}
+
+// Appends to `chunk` up to its capacity elements of the range in descending order.
+func (this *SortedMap) descendChunk(from interface{}, inclusive bool, to interface{}, chunk []Entry) []Entry {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	var node *skipNode
+	switch {
+	case from == nil:
+		node = this.last()
+	case inclusive:
+		node = this.floor(from)
+	default:
+		if node = this.findLess(from, nil); node == &this.head {
+			node = nil
+		}
+	}
+	for ; node != nil && len(chunk) < cap(chunk); node = node.prev {
+		if to != nil && this.compare(node.key, to) <= 0 {
+			break
+		}
+		chunk = append(chunk, Entry{Key: node.key, Value: node.value})
+	}
+	return chunk
+}
+
+// Returns the node with the greatest key less than or equal to `key`, or nil.
+func (this *SortedMap) floor(key interface{}) *skipNode {
+	less := this.findLess(key, nil)
+	if next := less.next[0]; next != nil && this.compare(next.key, key) == 0 {
+		return next
+	}
+	if less == &this.head {
+		return nil
+	}
+	return less
+}
+
+// Returns the node with the greatest key, or nil.
+func (this *SortedMap) last() *skipNode {
+	node := &this.head
+	for lev := this.level - 1; lev >= 0; lev-- {
+		for node.next[lev] != nil {
+			node = node.next[lev]
+		}
+	}
+	if node == &this.head {
+		return nil
+	}
+	return node
+}
+
+// Returns the element with the greatest key less than or equal to `key`, or false if there is none.
+func (this *SortedMap) Floor(key interface{}) (Entry, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return entryOf(this.floor(key))
+}
+
+// Returns the element with the least key greater than or equal to `key`, or false if there is none.
+func (this *SortedMap) Ceiling(key interface{}) (Entry, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return entryOf(this.findLess(key, nil).next[0])
+}
+
+// Returns the element with the least key, or false if the map is empty.
+func (this *SortedMap) Min() (Entry, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return entryOf(this.head.next[0])
+}
+
+// Returns the element with the greatest key, or false if the map is empty.
+func (this *SortedMap) Max() (Entry, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	return entryOf(this.last())
+}
+
+func entryOf(node *skipNode) (Entry, bool) {
+	if node == nil {
+		return Entry{}, false
+	}
+	return Entry{Key: node.key, Value: node.value}, true
+}
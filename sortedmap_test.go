@@ -0,0 +1,297 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap_test
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	. "github.com/gopot/concurrent-map"
+)
+
+func compareInts(a, b interface{}) int {
+	return cmp.Compare(a.(int), b.(int))
+}
+
+func TestSortedMapRanges(t *testing.T) {
+
+	testCases := []struct {
+		TestAlias string
+		QueryFn   func(sm *SortedMap) interface{}
+		Expected  interface{}
+	}{
+		{
+			TestAlias: "Ascend unbounded",
+			QueryFn:   func(sm *SortedMap) interface{} { return ascendKeys(sm, nil, nil) },
+			Expected:  []interface{}{10, 20, 30, 40, 50},
+		},
+		{
+			TestAlias: "Ascend from inclusive to exclusive",
+			QueryFn:   func(sm *SortedMap) interface{} { return ascendKeys(sm, 20, 40) },
+			Expected:  []interface{}{20, 30},
+		},
+		{
+			TestAlias: "Ascend between keys",
+			QueryFn:   func(sm *SortedMap) interface{} { return ascendKeys(sm, 15, 45) },
+			Expected:  []interface{}{20, 30, 40},
+		},
+		{
+			TestAlias: "Ascend empty range",
+			QueryFn:   func(sm *SortedMap) interface{} { return ascendKeys(sm, 31, 39) },
+			Expected:  []interface{}{},
+		},
+		{
+			TestAlias: "Descend unbounded",
+			QueryFn:   func(sm *SortedMap) interface{} { return descendKeys(sm, nil, nil) },
+			Expected:  []interface{}{50, 40, 30, 20, 10},
+		},
+		{
+			TestAlias: "Descend from inclusive to exclusive",
+			QueryFn:   func(sm *SortedMap) interface{} { return descendKeys(sm, 40, 20) },
+			Expected:  []interface{}{40, 30},
+		},
+		{
+			TestAlias: "Descend between keys",
+			QueryFn:   func(sm *SortedMap) interface{} { return descendKeys(sm, 45, 5) },
+			Expected:  []interface{}{40, 30, 20, 10},
+		},
+		{
+			TestAlias: "Floor and ceiling of present key",
+			QueryFn: func(sm *SortedMap) interface{} {
+				floor, _ := sm.Floor(30)
+				ceiling, _ := sm.Ceiling(30)
+				return []Entry{floor, ceiling}
+			},
+			Expected: []Entry{{Key: 30, Value: "30"}, {Key: 30, Value: "30"}},
+		},
+		{
+			TestAlias: "Floor and ceiling of absent key",
+			QueryFn: func(sm *SortedMap) interface{} {
+				floor, _ := sm.Floor(35)
+				ceiling, _ := sm.Ceiling(35)
+				return []Entry{floor, ceiling}
+			},
+			Expected: []Entry{{Key: 30, Value: "30"}, {Key: 40, Value: "40"}},
+		},
+		{
+			TestAlias: "Floor and ceiling out of range",
+			QueryFn: func(sm *SortedMap) interface{} {
+				_, floorOk := sm.Floor(5)
+				_, ceilingOk := sm.Ceiling(55)
+				return []bool{floorOk, ceilingOk}
+			},
+			Expected: []bool{false, false},
+		},
+		{
+			TestAlias: "Min and max",
+			QueryFn: func(sm *SortedMap) interface{} {
+				minimum, _ := sm.Min()
+				maximum, _ := sm.Max()
+				return []Entry{minimum, maximum}
+			},
+			Expected: []Entry{{Key: 10, Value: "10"}, {Key: 50, Value: "50"}},
+		},
+	}
+
+	sm := NewSorted(compareInts)
+	for _, key := range []int{30, 10, 50, 20, 40} {
+		sm.Set(key, fmt.Sprint(key))
+	}
+
+	for _, testCase := range testCases {
+		testAlias := testCase.TestAlias
+		queryFn := testCase.QueryFn
+		expected := testCase.Expected
+
+		testFn := func(t *testing.T) {
+
+			actual := queryFn(sm)
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("%s :: query returned %#v while expected %#v ", testAlias, actual, expected)
+			}
+		}
+		t.Run(testAlias, testFn)
+	}
+
+}
+
+func TestSortedMapEmpty(t *testing.T) {
+
+	sm := NewSorted(compareInts)
+
+	if _, ok := sm.Min(); ok {
+		t.Errorf("sm.Min() returned true for empty map ")
+	}
+	if _, ok := sm.Max(); ok {
+		t.Errorf("sm.Max() returned true for empty map ")
+	}
+	if keys := descendKeys(sm, nil, nil); len(keys) != 0 {
+		t.Errorf("sm.Descend(nil, nil, ...) visited %#v for empty map ", keys)
+	}
+
+}
+
+func TestSortedMapRandomOperations(t *testing.T) {
+
+	sm := NewSorted(compareInts)
+	expected := map[int]bool{}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		key := rnd.Intn(500)
+		if rnd.Intn(3) == 0 {
+			sm.Remove(key)
+			delete(expected, key)
+		} else {
+			sm.Set(key, key)
+			expected[key] = true
+		}
+	}
+
+	expectedKeys := []interface{}{}
+	for key := range expected {
+		expectedKeys = append(expectedKeys, key)
+	}
+	sort.Slice(expectedKeys, func(i, j int) bool { return expectedKeys[i].(int) < expectedKeys[j].(int) })
+	reversedKeys := make([]interface{}, len(expectedKeys))
+	for i, key := range expectedKeys {
+		reversedKeys[len(expectedKeys)-1-i] = key
+	}
+
+	if actualKeys := ascendKeys(sm, nil, nil); !reflect.DeepEqual(actualKeys, expectedKeys) {
+		t.Errorf("sm.Ascend(nil, nil, ...) visited %v while expected %v ", actualKeys, expectedKeys)
+	}
+	if actualKeys := descendKeys(sm, nil, nil); !reflect.DeepEqual(actualKeys, reversedKeys) {
+		t.Errorf("sm.Descend(nil, nil, ...) visited %v while expected %v ", actualKeys, reversedKeys)
+	}
+	if actualLen := sm.Len(); actualLen != len(expected) {
+		t.Errorf("sm.Len() returned %d while expected %d ", actualLen, len(expected))
+	}
+
+}
+
+func ascendKeys(sm *SortedMap, from, to interface{}) []interface{} {
+	keys := []interface{}{}
+	sm.Ascend(from, to, func(key, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func descendKeys(sm *SortedMap, from, to interface{}) []interface{} {
+	keys := []interface{}{}
+	sm.Descend(from, to, func(key, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestSortedMapRangeCallsMap(t *testing.T) {
+
+	sm := NewSorted(compareInts)
+	for i := 0; i < 200; i++ {
+		sm.Set(i, i)
+	}
+
+	// Calls map methods from `fn`, which would deadlock with a writer waiting if `fn` were called under the lock
+	visited := 0
+	sm.Ascend(nil, nil, func(key, value interface{}) bool {
+		if got, ok := sm.Get(key); !ok || got != value {
+			t.Errorf("sm.Get(%v) returned %v, %v while expected %v, true ", key, got, ok, value)
+		}
+		sm.Set(key, value.(int)*2)
+		visited++
+		return true
+	})
+	if visited != 200 {
+		t.Errorf("sm.Ascend(nil, nil, ...) visited %d elements while expected 200 ", visited)
+	}
+	if got, _ := sm.Get(199); got != 398 {
+		t.Errorf("sm.Get(199) returned %v while expected 398 set from fn ", got)
+	}
+
+	visited = 0
+	sm.Descend(nil, nil, func(key, value interface{}) bool {
+		sm.Remove(key)
+		visited++
+		return true
+	})
+	if visited != 200 || sm.Len() != 0 {
+		t.Errorf("sm.Descend(nil, nil, ...) visited %d elements and left %d while expected 200 and 0 ", visited, sm.Len())
+	}
+
+}
+
+func TestSortedMapBatchAndTryMethods(t *testing.T) {
+
+	sm := NewSorted(compareInts)
+	sm.SetMany(map[interface{}]interface{}{3: "c", 1: "a"})
+	if inserted := sm.SetManyIfNotExists(map[interface{}]interface{}{1: "x", 2: "b"}); !reflect.DeepEqual(inserted, []interface{}{2}) {
+		t.Errorf("sm.SetManyIfNotExists(...) returned %#v while expected [2] ", inserted)
+	}
+	found, missing := sm.GetMany([]interface{}{1, 4})
+	if !reflect.DeepEqual(found, map[interface{}]interface{}{1: "a"}) || !reflect.DeepEqual(missing, []interface{}{4}) {
+		t.Errorf("sm.GetMany(...) returned %#v, %#v while expected {1: a}, [4] ", found, missing)
+	}
+	sm.RemoveMany([]interface{}{3, 4})
+	if keys := ascendKeys(sm, nil, nil); !reflect.DeepEqual(keys, []interface{}{1, 2}) {
+		t.Errorf("sm.Ascend(nil, nil, ...) visited %#v while expected [1 2] ", keys)
+	}
+
+	if err := sm.TrySet("one", 1); !errors.Is(err, ErrKeyType) {
+		t.Errorf("sm.TrySet(\"one\") returned %v while expected ErrKeyType ", err)
+	}
+	if _, _, err := sm.TryGet("one"); !errors.Is(err, ErrKeyType) {
+		t.Errorf("sm.TryGet(\"one\") returned %v while expected ErrKeyType ", err)
+	}
+	if _, err := sm.TrySetIfNotExists("one", 1); !errors.Is(err, ErrKeyType) {
+		t.Errorf("sm.TrySetIfNotExists(\"one\") returned %v while expected ErrKeyType ", err)
+	}
+	if err := sm.TryRemove("one"); !errors.Is(err, ErrKeyType) {
+		t.Errorf("sm.TryRemove(\"one\") returned %v while expected ErrKeyType ", err)
+	}
+	if err := sm.TrySet(0, "zero"); err != nil {
+		t.Errorf("sm.TrySet(0) returned unexpected error %v ", err)
+	}
+}
+
+func TestSortedMapJSON(t *testing.T) {
+
+	sm := NewSorted(func(a, b interface{}) int { return cmp.Compare(a.(string), b.(string)) })
+	if err := json.Unmarshal([]byte(`{"2024-03-02": 2, "2024-01-05": {"count": 1}, "2024-02-11": 3}`), sm); err != nil {
+		t.Fatalf("json.Unmarshal(...) returned unexpected error %v ", err)
+	}
+	actualJSON, err := json.Marshal(sm)
+	if err != nil {
+		t.Fatalf("json.Marshal(sm) returned unexpected error %v ", err)
+	}
+	if expected := `{"2024-01-05":{"count":1},"2024-02-11":3,"2024-03-02":2}`; string(actualJSON) != expected {
+		t.Errorf("json.Marshal(sm) returned %s while expected %s ", actualJSON, expected)
+	}
+
+	ints := NewSorted(compareInts)
+	if err := json.Unmarshal([]byte(`{"1": 1}`), ints); !errors.Is(err, ErrKeyType) || ints.Len() != 0 {
+		t.Errorf("json.Unmarshal(...) into int keyed map returned %v and set %d elements while expected ErrKeyType ", err, ints.Len())
+	}
+}
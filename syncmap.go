@@ -0,0 +1,70 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "sync"
+
+// The SyncMap type represents Map backed by sync.Map.
+//
+// It suits the workloads sync.Map is optimized for: keys written once and read many times,
+// or goroutines working on disjoint sets of keys. The zero value is an empty map ready to use.
+type SyncMap struct {
+	items sync.Map
+}
+
+// Instantiates and initializes SyncMap with the copy of `m`.
+func NewSyncMap(m map[interface{}]interface{}) *SyncMap {
+	cm := &SyncMap{}
+	for key, value := range m {
+		cm.items.Store(key, value)
+	}
+	return cm
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *SyncMap) Get(key interface{}) (interface{}, bool) {
+	return this.items.Load(key)
+}
+
+// Sets the given value under the specified key.
+func (this *SyncMap) Set(key interface{}, val interface{}) {
+	this.items.Store(key, val)
+}
+
+// Sets the given value under the specified key and returns true, if the key didn't exist upon invokation.
+// Returns false and does nothing, in case there is already an entry with the same key.
+func (this *SyncMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	_, loaded := this.items.LoadOrStore(key, val)
+	return !loaded
+}
+
+// Removes an element from the map.
+func (this *SyncMap) Remove(key interface{}) {
+	this.items.Delete(key)
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+//
+// NOTE: unlike other implementations, the copy is not a consistent snapshot,
+// as sync.Map does not provide one: it may reflect some of concurrent modifications only.
+func (this *SyncMap) Items() map[interface{}]interface{} {
+	x := make(map[interface{}]interface{})
+	this.items.Range(func(key, value interface{}) bool {
+		x[key] = value
+		return true
+	})
+	return x
+}
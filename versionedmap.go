@@ -0,0 +1,232 @@
+//   Copyright 2015-2017 Ivan A Kostko (github.com/ivan-kostko; github.com/gopot)
+
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+
+//       http://www.apache.org/licenses/LICENSE-2.0
+
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package concurrentmap
+
+import "sync/atomic"
+
+// The VersionedMap type represents concurrent-safe map publishing immutable PersistentMap versions.
+//
+// Reads are a single atomic load and never block. Every write derives a new version, sharing structure
+// with the previous one, and publishes it with compare-and-swap, retrying if another writer got ahead.
+// So writes cost O(log n) and Snapshot returns a point-in-time version for free.
+//
+// SetMany, SetManyIfNotExists, RemoveMany and UnmarshalJSON publish a single version each, so readers see either none
+// or all of their changes. Checked methods such as TrySet return ErrUnhashableKey instead of panicking.
+// The zero value is an empty map ready to use.
+type VersionedMap struct {
+	current atomic.Pointer[PersistentMap]
+}
+
+// Instantiates and initializes VersionedMap with the copy of `m`.
+func NewVersioned(m map[interface{}]interface{}) *VersionedMap {
+	pm := NewPersistent()
+	for key, value := range m {
+		pm = pm.With(key, value)
+	}
+	vm := &VersionedMap{}
+	vm.current.Store(pm)
+	return vm
+}
+
+// Derives a new version from the current one with `fn` and publishes it.
+// Nothing is published if `fn` returns the version it was given.
+func (this *VersionedMap) update(fn func(pm *PersistentMap) *PersistentMap) {
+	for {
+		old := this.current.Load()
+		new := fn(old)
+		if new == old || this.current.CompareAndSwap(old, new) {
+			return
+		}
+	}
+}
+
+// Retrieves an element from map under given key.
+// Returns false in case there is no entry associated with the key.
+func (this *VersionedMap) Get(key interface{}) (interface{}, bool) {
+	return this.current.Load().Get(key)
+}
+
+// Sets the given value under the specified key.
+func (this *VersionedMap) Set(key interface{}, val interface{}) {
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		return pm.With(key, val)
+	})
+}
+
+// Sets the given value under the specified key if no value was associated with it.
+// Returns true if the value was set.
+func (this *VersionedMap) SetIfNotExists(key interface{}, val interface{}) bool {
+	isSet := false
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		if _, ok := pm.Get(key); ok {
+			isSet = false
+			return pm
+		}
+		isSet = true
+		return pm.With(key, val)
+	})
+	return isSet
+}
+
+// Removes an element from the map.
+func (this *VersionedMap) Remove(key interface{}) {
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		return pm.Without(key)
+	})
+}
+
+// Returns copy of content as non concurrent(general) `map[interface{}]interface{}`.
+func (this *VersionedMap) Items() map[interface{}]interface{} {
+	return this.current.Load().Items()
+}
+
+// Returns number of elements in the map.
+func (this *VersionedMap) Len() int {
+	return this.current.Load().Len()
+}
+
+// Retrieves an element from map under given key, same as Get.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be hashed.
+func (this *VersionedMap) TryGet(key interface{}) (interface{}, bool, error) {
+	if err := checkHashable(key); err != nil {
+		return nil, false, err
+	}
+	val, ok := this.Get(key)
+	return val, ok, nil
+}
+
+// Sets the given value under the specified key, same as Set.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be hashed.
+func (this *VersionedMap) TrySet(key interface{}, val interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Set(key, val)
+	return nil
+}
+
+// Sets the given value under the specified key if no value was associated with it, same as SetIfNotExists.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be hashed.
+func (this *VersionedMap) TrySetIfNotExists(key interface{}, val interface{}) (bool, error) {
+	if err := checkHashable(key); err != nil {
+		return false, err
+	}
+	return this.SetIfNotExists(key, val), nil
+}
+
+// Removes an element from the map, same as Remove.
+// Returns ErrUnhashableKey instead of panicking if the key cannot be hashed.
+func (this *VersionedMap) TryRemove(key interface{}) error {
+	if err := checkHashable(key); err != nil {
+		return err
+	}
+	this.Remove(key)
+	return nil
+}
+
+// Sets all the given entries in a single new version.
+func (this *VersionedMap) SetMany(entries map[interface{}]interface{}) {
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		for key, val := range entries {
+			pm = pm.With(key, val)
+		}
+		return pm
+	})
+}
+
+// Sets the given entries whose keys didn't exist upon invokation, in a single new version.
+// Returns keys of inserted entries; entries with already existing keys are left untouched.
+func (this *VersionedMap) SetManyIfNotExists(entries map[interface{}]interface{}) []interface{} {
+	var inserted []interface{}
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		// A retried update starts over from a newer version
+		inserted = make([]interface{}, 0, len(entries))
+		for key, val := range entries {
+			if _, ok := pm.Get(key); !ok {
+				pm = pm.With(key, val)
+				inserted = append(inserted, key)
+			}
+		}
+		return pm
+	})
+	return inserted
+}
+
+// Retrieves elements under given keys from a single version of the map.
+// Returns found entries and keys which have no entry associated, in the order they were given.
+func (this *VersionedMap) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	pm := this.current.Load()
+	found := make(map[interface{}]interface{}, len(keys))
+	missing := []interface{}{}
+	for _, key := range keys {
+		if val, ok := pm.Get(key); ok {
+			found[key] = val
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return found, missing
+}
+
+// Removes elements under given keys in a single new version.
+func (this *VersionedMap) RemoveMany(keys []interface{}) {
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		for _, key := range keys {
+			pm = pm.Without(key)
+		}
+		return pm
+	})
+}
+
+// Implements [Marshaler](https://golang.org/pkg/encoding/json/#Marshaler), see ConcurrentMap.MarshalJSON.
+// The currently published version is rendered, so it never blocks writers.
+func (this *VersionedMap) MarshalJSON() ([]byte, error) {
+	return marshalJSONEntries(this)
+}
+
+func (this *VersionedMap) jsonEntries() []Entry {
+	entries := []Entry{}
+	this.Snapshot().Range(func(key, value interface{}) bool {
+		entries = append(entries, Entry{Key: key, Value: value})
+		return true
+	})
+	sortEntries(entries)
+	return entries
+}
+
+// Implements [Unmarshaller](https://golang.org/pkg/encoding/json/#Unmarshaler), see ConcurrentMap.UnmarshalJSON.
+// All decoded entries are published in a single new version.
+func (this *VersionedMap) UnmarshalJSON(data []byte) error {
+	entries, err := decodeJSONEntries(data)
+	if err != nil {
+		return err
+	}
+	this.update(func(pm *PersistentMap) *PersistentMap {
+		for _, entry := range entries {
+			pm = pm.With(entry.Key, entry.Value)
+		}
+		return pm
+	})
+	return nil
+}
+
+// Returns currently published version, which stays unchanged by subsequent modifications of the map.
+func (this *VersionedMap) Snapshot() *PersistentMap {
+	pm := this.current.Load()
+	if pm == nil {
+		return NewPersistent()
+	}
+	return pm
+}